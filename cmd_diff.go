@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"math"
+	"sort"
+	"time"
+)
+
+// FieldDiff ...
+type FieldDiff struct {
+	Name  string
+	A     float64
+	B     float64
+	Delta float64
+}
+
+// SnapshotDiff ...
+type SnapshotDiff struct {
+	A       time.Time
+	B       time.Time
+	Fields  []FieldDiff
+	Symbols []FieldDiff
+}
+
+func cmdDiff() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Print the changes between the snapshots of two dates",
+		ArgsUsage: "<date-a> <date-b>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return fmt.Errorf("Pass two dates like 2021-06-04 now")
+			}
+
+			a, err := parseSnapshotDate(c.Args().Get(0))
+			if err != nil {
+				return err
+			}
+
+			b, err := parseSnapshotDate(c.Args().Get(1))
+			if err != nil {
+				return err
+			}
+
+			conf, filename, err := loadConf(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			history := loadHistory(conf, filename)
+			if len(history) == 0 {
+				return fmt.Errorf("No snapshots found")
+			}
+
+			printSnapshotDiff(diffSnapshots(nearestSnapshot(history, a), nearestSnapshot(history, b)))
+			return nil
+		},
+	}
+}
+
+// parseSnapshotDate parses a date, a date with time, now or a period before
+// now like 7d.
+func parseSnapshotDate(s string) (time.Time, error) {
+	if s == "now" {
+		return time.Now(), nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	d, err := parsePeriod(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date %q", s)
+	}
+
+	return time.Now().Add(-d), nil
+}
+
+func nearestSnapshot(history []Out, t time.Time) Out {
+	nearest := history[0]
+	for _, out := range history {
+		if math.Abs(float64(out.Date.Sub(t))) < math.Abs(float64(nearest.Date.Sub(t))) {
+			nearest = out
+		}
+	}
+
+	return nearest
+}
+
+// diffSnapshots returns the delta of every field and, if both snapshots
+// contain details, of the value of every symbol.
+func diffSnapshots(a Out, b Out) SnapshotDiff {
+	diff := SnapshotDiff{A: a.Date, B: b.Date}
+	diff.Fields = diffValues(outValues(a), outValues(b))
+
+	symbolsA := symbolValues(a)
+	symbolsB := symbolValues(b)
+	if len(symbolsA) > 0 && len(symbolsB) > 0 {
+		diff.Symbols = diffValues(symbolsA, symbolsB)
+	}
+
+	return diff
+}
+
+func diffValues(a map[string]float64, b map[string]float64) []FieldDiff {
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	diffs := []FieldDiff{}
+	for _, name := range names {
+		diffs = append(diffs, FieldDiff{name, a[name], b[name], b[name] - a[name]})
+	}
+
+	return diffs
+}
+
+func symbolValues(out Out) map[string]float64 {
+	values := make(map[string]float64)
+	for _, stats := range []InvestmentStats{out.Stocks, out.Assets, out.Crypto} {
+		for symbol, s := range stats.Symbols {
+			values[symbol] = values[symbol] + s.Value
+		}
+	}
+
+	return values
+}
+
+func printSnapshotDiff(diff SnapshotDiff) {
+	fmt.Fprintf(notes(), "%s -> %s\n", diff.A.Local().Format("2006-01-02 15:04"), diff.B.Local().Format("2006-01-02 15:04"))
+
+	if isStructuredOutput() {
+		printData(diff)
+		return
+	}
+
+	if outputFormat == outputCSV {
+		printDiffRecords(diff)
+		return
+	}
+
+	printFieldDiffs("Field", diff.Fields)
+	if len(diff.Symbols) > 0 {
+		printFieldDiffs("Symbol", diff.Symbols)
+	}
+}
+
+func printFieldDiffs(name string, diffs []FieldDiff) {
+	table := newTable()
+	table.SetHeader([]string{name, "A", "B", "Delta"})
+
+	for _, d := range diffs {
+		row := []string{
+			d.Name,
+			fmt.Sprintf("%.2f", d.A),
+			fmt.Sprintf("%.2f", d.B),
+			fmt.Sprintf("%+.2f", d.Delta),
+		}
+
+		color := tablewriter.FgGreenColor
+		if d.Delta < 0 {
+			color = tablewriter.FgRedColor
+		}
+
+		table.Rich(row, []tablewriter.Colors{
+			{},
+			{},
+			{},
+			{tablewriter.Bold, color},
+		})
+	}
+
+	table.Render(diffs)
+}
+
+// printDiffRecords prints the fields and symbols as one table with their
+// kind, since a CSV file has only room for one table.
+func printDiffRecords(diff SnapshotDiff) {
+	table := newTable()
+	table.SetHeader([]string{"Kind", "Name", "A", "B", "Delta"})
+
+	for _, kind := range []struct {
+		name  string
+		diffs []FieldDiff
+	}{{"field", diff.Fields}, {"symbol", diff.Symbols}} {
+		for _, d := range kind.diffs {
+			table.Append([]string{
+				kind.name,
+				d.Name,
+				fmt.Sprintf("%.2f", d.A),
+				fmt.Sprintf("%.2f", d.B),
+				fmt.Sprintf("%+.2f", d.Delta),
+			})
+		}
+	}
+
+	table.Render(diff)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FXRate ...
+type FXRate struct {
+	From string
+	To   string
+	Date string
+	Rate float64
+}
+
+// ecbEnvelope is the XML of the ECB reference rates with the rates of every
+// day in units per EUR.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func cmdFX() *cli.Command {
+	return &cli.Command{
+		Name:  "fx",
+		Usage: "Exchange rates",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "rate",
+				Usage:     "Print the rate of two currencies",
+				ArgsUsage: "<from> <to>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Value:   "",
+						Usage:   "finance config",
+					},
+					&cli.StringFlag{
+						Name:  "date",
+						Value: "",
+						Usage: "date like 2021-03-01, default is now",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					currencies, date, err := fxRateArgs(c.Args().Slice(), c.String("date"))
+					if err != nil {
+						return err
+					}
+
+					_, _, err = loadConf(c.String("file"))
+					if err != nil && !os.IsNotExist(err) {
+						return err
+					}
+
+					rate, err := getFXRate(currencies[0], currencies[1], date)
+					if err != nil {
+						return err
+					}
+
+					printFXRate(rate)
+					return nil
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the ECB reference rates from eurofxref-hist as csv, xml or zip",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Value:   "",
+						Usage:   "finance config",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("Usage: fin-stats fx import <file>")
+					}
+
+					_, filename, err := loadConf(c.String("file"))
+					if err != nil {
+						return err
+					}
+
+					if _, err := getOutDir(filename); err != nil {
+						return err
+					}
+
+					rates, err := readECBFile(c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					n, err := fxStore.add(rates)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Imported %d rates of %d currencies\n", n, len(rates))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// fxRateArgs returns the currencies and the date of fx rate. The cli stops
+// parsing flags at the first argument, so a date after the currencies is
+// read here. Other flags must come first.
+func fxRateArgs(args []string, date string) ([]string, string, error) {
+	currencies := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--date" || arg == "-date":
+			if i+1 == len(args) {
+				return nil, "", fmt.Errorf("Flag needs an argument: %s", arg)
+			}
+
+			i++
+			date = args[i]
+		case strings.HasPrefix(arg, "--date=") || strings.HasPrefix(arg, "-date="):
+			date = arg[strings.Index(arg, "=")+1:]
+		case strings.HasPrefix(arg, "-"):
+			return nil, "", fmt.Errorf("Flag %s must come before the currencies", arg)
+		default:
+			currencies = append(currencies, arg)
+		}
+	}
+
+	if len(currencies) != 2 {
+		return nil, "", fmt.Errorf("Usage: fin-stats fx rate [--date 2021-03-01] <from> <to>")
+	}
+
+	return currencies, date, nil
+}
+
+// getFXRate returns the units of to per unit of from at a date.
+func getFXRate(from string, to string, date string) (FXRate, error) {
+	result := FXRate{From: from, To: to, Date: date}
+	for _, currency := range []string{from, to} {
+		if err := validateCurrency(currency); err != nil {
+			return result, err
+		}
+	}
+
+	at := time.Time{}
+	if date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return result, err
+		}
+
+		at = t
+	} else {
+		result.Date = time.Now().Format("2006-01-02")
+	}
+
+	fromRate, err := getCurrencyAt(from, at)
+	if err == nil && fromRate == 0 {
+		err = fmt.Errorf("Could not fetch rate for %s", from)
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	toRate, err := getCurrencyAt(to, at)
+	if err == nil && toRate == 0 {
+		err = fmt.Errorf("Could not fetch rate for %s", to)
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	result.Rate = toRate / fromRate
+	return result, nil
+}
+
+func printFXRate(rate FXRate) {
+	table := newTable()
+	table.SetHeader([]string{"From", "To", "Date", "Rate"})
+	table.Append([]string{rate.From, rate.To, rate.Date, strconv.FormatFloat(rate.Rate, 'f', 6, 64)})
+	table.Render(rate)
+}
+
+// readECBFile reads the rates of an ECB reference rates file and converts
+// them from units per EUR to units per USD. Days without USD rate are left
+// out.
+func readECBFile(path string) (map[string]map[string]float64, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		buf, path, err = unzipFirst(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	perEUR := make(map[string]map[string]float64)
+	if bytes.HasPrefix(bytes.TrimSpace(buf), []byte("<")) {
+		err = parseECBXML(buf, perEUR)
+	} else {
+		err = parseECBCSV(buf, perEUR)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("in file %q: %v", path, err)
+	}
+
+	rates := make(map[string]map[string]float64)
+	for date, day := range perEUR {
+		usd, ok := day["USD"]
+		if !ok || usd == 0 {
+			continue
+		}
+
+		day["EUR"] = 1
+		for currency, rate := range day {
+			if currency == "USD" {
+				continue
+			}
+
+			if rates[currency] == nil {
+				rates[currency] = make(map[string]float64)
+			}
+
+			rates[currency][date] = rate / usd
+		}
+	}
+
+	return rates, nil
+}
+
+func unzipFirst(buf []byte) ([]byte, string, error) {
+	r, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(r.File) == 0 {
+		return nil, "", fmt.Errorf("Empty zip file")
+	}
+
+	f, err := r.File[0].Open()
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	return data, r.File[0].Name, err
+}
+
+// parseECBCSV parses a csv with a Date column and a column per currency.
+// Missing rates are N/A.
+func parseECBCSV(buf []byte, perEUR map[string]map[string]float64) error {
+	r := csv.NewReader(bytes.NewReader(buf))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		date := strings.TrimSpace(record[0])
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return err
+		}
+
+		day := make(map[string]float64)
+		for i := 1; i < len(record) && i < len(header); i++ {
+			rate, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+			currency := strings.TrimSpace(header[i])
+			if err == nil && currency != "" {
+				day[currency] = rate
+			}
+		}
+
+		perEUR[date] = day
+	}
+}
+
+func parseECBXML(buf []byte, perEUR map[string]map[string]float64) error {
+	envelope := ecbEnvelope{}
+	err := xml.Unmarshal(buf, &envelope)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range envelope.Days {
+		day := make(map[string]float64)
+		for _, r := range d.Rates {
+			day[r.Currency] = r.Rate
+		}
+
+		perEUR[d.Time] = day
+	}
+
+	return nil
+}
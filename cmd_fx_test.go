@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func readECBFixture(t *testing.T, name string, content string) map[string]map[string]float64 {
+	path := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := readECBFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rates
+}
+
+func TestReadECBFileCSV(t *testing.T) {
+	rates := readECBFixture(t, "eurofxref-hist.csv", "Date,USD,JPY,CYP,\n"+
+		"2022-06-01,1.0736,139.56,N/A,\n"+
+		"2022-05-31,N/A,138.0,N/A,\n")
+
+	if _, ok := rates["CYP"]; ok {
+		t.Error("got CYP rates from N/A cells")
+	}
+
+	if _, ok := rates["JPY"]["2022-05-31"]; ok {
+		t.Error("got JPY rate of a day without USD rate")
+	}
+
+	assertFloat(t, "EUR", rates["EUR"]["2022-06-01"], 1/1.0736)
+	assertFloat(t, "JPY", rates["JPY"]["2022-06-01"], 139.56/1.0736)
+	if len(rates) != 2 {
+		t.Errorf("got rates of %d currencies, want EUR and JPY", len(rates))
+	}
+}
+
+func TestReadECBFileXML(t *testing.T) {
+	rates := readECBFixture(t, "eurofxref-hist.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2022-06-01">
+			<Cube currency="USD" rate="1.0736"/>
+			<Cube currency="GBP" rate="0.85"/>
+		</Cube>
+		<Cube time="2022-05-31">
+			<Cube currency="USD" rate="1.0772"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`)
+
+	assertFloat(t, "GBP", rates["GBP"]["2022-06-01"], 0.85/1.0736)
+	assertFloat(t, "EUR", rates["EUR"]["2022-05-31"], 1/1.0772)
+	if _, ok := rates["USD"]; ok {
+		t.Error("got USD rates")
+	}
+}
+
+func TestFXStoreBrokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	broken := "date,currency,rate\n2022-06-01,EUR,abc\n"
+	err := ioutil.WriteFile(path, []byte(broken), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &FXStore{path: path}
+	for i := 0; i < 2; i++ {
+		if _, err := s.add(map[string]map[string]float64{"EUR": {"2022-06-02": 0.93}}); err == nil {
+			t.Errorf("add %d: got no error for a broken file", i)
+		}
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != broken {
+		t.Errorf("broken file was changed:\n%s", buf)
+	}
+}
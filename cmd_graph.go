@@ -3,10 +3,9 @@ package main
 import (
 	"fmt"
 	"github.com/guptarohit/asciigraph"
-	"github.com/piquette/finance-go/chart"
-	"github.com/piquette/finance-go/datetime"
 	"github.com/urfave/cli/v2"
 	"log"
+	"math"
 	"time"
 )
 
@@ -23,6 +22,11 @@ func cmdGraph() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			err := loadProvider()
+			if err != nil {
+				return err
+			}
+
 			if c.NArg() > 0 {
 				graph(c.Args().Get(0), c.String("period"))
 				return nil
@@ -34,68 +38,82 @@ func cmdGraph() *cli.Command {
 }
 
 func graph(symbol string, period string) {
-	var start int
-	var interval datetime.Interval
-	end := int(time.Now().Unix())
+	bars, err := getChart(symbol, period)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if outputFormat != outputTable {
+		printBars(bars)
+		return
+	}
+
+	// Bars keep the full precision, which is needed for exchange rates.
+	data := []float64{}
+	for _, b := range bars {
+		data = append(data, math.Round(b.Close*100)/100)
+	}
+
+	graph := asciigraph.Plot(data, asciigraph.Height(16))
+	fmt.Println(graph)
+}
+
+// getChart returns the bars of a period like 1d, 1wk, 2wk, 6mo, 1yr or 5yr,
+// default is one month, and the current price as the last bar.
+func getChart(symbol string, period string) ([]Bar, error) {
+	now := time.Now()
+	params := ChartParams{End: now}
 
 	switch period {
 	case "1d":
-		end = 0
-		interval = datetime.FiveMins
+		params.End = time.Time{}
+		params.Interval = "5m"
 	case "1wk":
-		start = int(time.Now().Add(-7 * 24 * time.Hour).Unix())
-		interval = datetime.OneHour
+		params.Start = now.Add(-7 * 24 * time.Hour)
+		params.Interval = "1h"
 	case "2wk":
-		start = int(time.Now().Add(-14 * 24 * time.Hour).Unix())
-		interval = datetime.OneHour
+		params.Start = now.Add(-14 * 24 * time.Hour)
+		params.Interval = "1h"
 	case "6mo":
-		start = int(time.Now().Add(-6 * 30 * 24 * time.Hour).Unix())
-		interval = datetime.FiveDay
+		params.Start = now.Add(-6 * 30 * 24 * time.Hour)
+		params.Interval = "5d"
 	case "1yr":
-		start = int(time.Now().Add(-12 * 30 * 24 * time.Hour).Unix())
-		interval = datetime.FiveDay
+		params.Start = now.Add(-12 * 30 * 24 * time.Hour)
+		params.Interval = "5d"
 	case "5yr":
-		start = int(time.Now().Add(-5 * 12 * 30 * 24 * time.Hour).Unix())
-		interval = datetime.OneMonth
+		params.Start = now.Add(-5 * 12 * 30 * 24 * time.Hour)
+		params.Interval = "1mo"
 	default:
-		start = int(time.Now().Add(-30 * 24 * time.Hour).Unix())
-		interval = datetime.OneDay
+		params.Start = now.Add(-30 * 24 * time.Hour)
+		params.Interval = "1d"
 	}
 
 	// fetch chart bars.
-	params := &chart.Params{
-		Symbol:   symbol,
-		Interval: interval,
-	}
-	if start != 0 {
-		params.Start = datetime.FromUnix(start)
-	}
-	if end != 0 {
-		params.End = datetime.FromUnix(end)
-	}
-
-	iter := chart.Get(params)
-
-	data := []float64{}
-	for iter.Next() {
-		b := iter.Bar()
-		fl, _ := b.Close.Round(2).Float64()
-		data = append(data, fl)
-	}
-	if iter.Err() != nil {
-		log.Fatal(iter.Err())
+	bars, err := provider.Chart(symbol, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// Append current (pre/post market) price
 	q, err := getQuote(symbol, true)
 	if err == nil {
-		data = append(data, q.Price)
+		bars = append(bars, Bar{now, q.Price})
 	}
 
-	if len(data) == 0 {
-		log.Fatal("Could not find chart data")
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("Could not find chart data")
 	}
 
-	graph := asciigraph.Plot(data, asciigraph.Height(16))
-	fmt.Println(graph)
+	return bars, nil
+}
+
+func printBars(bars []Bar) {
+	table := newTable()
+	table.SetHeader([]string{"Time", "Close"})
+
+	for _, b := range bars {
+		table.Append([]string{b.Time.Format(time.RFC3339), formatPrice(b.Close)})
+	}
+
+	table.Render(bars)
 }
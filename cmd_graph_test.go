@@ -0,0 +1,45 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetChart(t *testing.T) {
+	useFixtures(t, map[string]Quote{"EUR=X": {Price: 0.9215}}, nil)
+
+	now := time.Now()
+	writeFixture(t, filepath.Join(fixturesDir, "charts", "EUR=X.yaml"), []Bar{
+		{Time: now.AddDate(0, -2, 0), Close: 0.95},
+		{Time: now.AddDate(0, 0, -2), Close: 0.91234},
+		{Time: now.AddDate(0, 0, -1), Close: 0.91876},
+	})
+
+	bars, err := getChart("EUR=X", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bars) != 3 {
+		t.Fatalf("got %d bars, want the 2 bars of the month and the quote", len(bars))
+	}
+
+	assertFloat(t, "first close", bars[0].Close, 0.91234)
+	assertFloat(t, "last close", bars[2].Close, 0.9215)
+}
+
+func TestGraph(t *testing.T) {
+	useFixtures(t, map[string]Quote{"AAPL": {Price: 181.236}}, nil)
+	writeFixture(t, filepath.Join(fixturesDir, "charts", "AAPL.yaml"), []Bar{
+		{Time: time.Now().AddDate(0, 0, -1), Close: 179.004},
+	})
+
+	s := captureStdout(t, func() { graph("AAPL", "") })
+	for _, want := range []string{"181 ┤", "179 ┼"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("graph misses %q:\n%s", want, s)
+		}
+	}
+}
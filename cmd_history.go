@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// HistoryRow ...
+type HistoryRow struct {
+	Date       time.Time
+	Total      float64
+	Savings    float64
+	StocksSum  float64 `yaml:"stocks_sum"`
+	StocksDiff float64 `yaml:"stocks_diff"`
+	AssetsSum  float64 `yaml:"assets_sum"`
+	AssetsDiff float64 `yaml:"assets_diff"`
+	CryptoSum  float64 `yaml:"crypto_sum"`
+	CryptoDiff float64 `yaml:"crypto_diff"`
+}
+
+// HistoryQuery ...
+type HistoryQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+func cmdHistory() *cli.Command {
+	return &cli.Command{
+		Name:  "history",
+		Usage: "Print the history of sum snapshots",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+			&cli.StringFlag{
+				Name:  "from",
+				Value: "",
+				Usage: "start date like 2021-01-31",
+			},
+			&cli.StringFlag{
+				Name:  "to",
+				Value: "",
+				Usage: "end date like 2021-12-31",
+			},
+			&cli.StringFlag{
+				Name:  "last",
+				Value: "",
+				Usage: "period until now like 12h, 30d, 8w, 6m or 1y",
+			},
+			&cli.StringFlag{
+				Name:    "interval",
+				Aliases: []string{"i"},
+				Value:   "",
+				Usage:   "daily, weekly or monthly. Keeps the last snapshot of each",
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:  "compact",
+				Usage: "Remove snapshots by the retention rules of the config",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Value:   "",
+						Usage:   "finance config",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Value: false,
+						Usage: "only print the number of removed snapshots",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					conf, filename, err := loadConf(c.String("file"))
+					if err != nil {
+						return err
+					}
+
+					if len(conf.Snapshot.Retention) == 0 {
+						return fmt.Errorf("No retention rules in config")
+					}
+
+					removed, total, err := compactHistory(conf, filename, c.Bool("dry-run"))
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Removed %d of %d snapshots\n", removed, total)
+					return nil
+				},
+			},
+			{
+				Name:  "migrate",
+				Usage: "Copy the snapshot files of the finances dir to a jsonl or csv store",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Value:   "",
+						Usage:   "finance config",
+					},
+					&cli.StringFlag{
+						Name:  "store",
+						Value: "",
+						Usage: "jsonl or csv, default is the store of the config",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Value: "",
+						Usage: "target file, default is the file of the config",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					conf, filename, err := loadConf(c.String("file"))
+					if err != nil {
+						return err
+					}
+
+					target := conf.Snapshot
+					if c.IsSet("store") {
+						target.Store = c.String("store")
+					}
+
+					if c.IsSet("out") {
+						target.File, err = filepath.Abs(c.String("out"))
+						if err != nil {
+							return err
+						}
+					}
+
+					err = validateStore(target)
+					if err != nil {
+						return err
+					}
+
+					n, err := migrateHistory(target, filename)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Migrated %d snapshots, set snapshot.store to %s in the config to use them\n", n, target.Store)
+					return nil
+				},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			query, err := parseHistoryQuery(c.String("from"), c.String("to"), c.String("last"), c.String("interval"))
+			if err != nil {
+				return err
+			}
+
+			conf, filename, err := loadConf(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			printHistoryTable(queryHistory(loadHistory(conf, filename), query))
+			return nil
+		},
+	}
+}
+
+func parseHistoryQuery(from string, to string, last string, interval string) (HistoryQuery, error) {
+	query := HistoryQuery{Interval: interval}
+
+	switch interval {
+	case "", "daily", "weekly", "monthly":
+	default:
+		return query, fmt.Errorf("Unknown interval %q, allowed: daily,weekly,monthly", interval)
+	}
+
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return query, err
+		}
+
+		query.From = t
+	}
+
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return query, err
+		}
+
+		query.To = t.AddDate(0, 0, 1)
+	}
+
+	if last != "" {
+		d, err := parsePeriod(last)
+		if err != nil {
+			return query, err
+		}
+
+		query.From = time.Now().Add(-d)
+	}
+
+	return query, nil
+}
+
+var periodPattern = regexp.MustCompile(`^(\d+)(d|w|mo|y)$`)
+
+// parsePeriod parses a Go duration like 90m or 1h30m, or a count of days
+// with the units d, w, mo and y. Months have 30 and years 365 days.
+func parsePeriod(s string) (time.Duration, error) {
+	days := map[string]int{"d": 1, "w": 7, "mo": 30, "y": 365}
+	if m := periodPattern.FindStringSubmatch(s); m != nil {
+		count, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, fmt.Errorf("Invalid period %q", s)
+		}
+
+		return time.Duration(count*days[m[2]]) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid period %q, expected a duration like 1h30m or a count of d, w, mo or y", s)
+	}
+
+	return d, nil
+}
+
+// queryHistory returns the snapshots in the range of the query sorted by
+// date. With an interval only the last snapshot of each day, week or month
+// is kept.
+func queryHistory(history []Out, query HistoryQuery) []Out {
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Date.Before(history[j].Date)
+	})
+
+	result := []Out{}
+	bucket := ""
+
+	for _, out := range history {
+		if !query.From.IsZero() && out.Date.Before(query.From) {
+			continue
+		}
+
+		if !query.To.IsZero() && !out.Date.Before(query.To) {
+			continue
+		}
+
+		key := historyBucket(out.Date, query.Interval)
+		if key != "" && key == bucket {
+			result[len(result)-1] = out
+			continue
+		}
+
+		bucket = key
+		result = append(result, out)
+	}
+
+	return result
+}
+
+func historyBucket(t time.Time, interval string) string {
+	t = t.Local()
+	switch interval {
+	case "daily":
+		return t.Format("2006-01-02")
+	case "weekly":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "monthly":
+		return t.Format("2006-01")
+	}
+
+	return ""
+}
+
+func getHistoryRows(history []Out) []HistoryRow {
+	rows := []HistoryRow{}
+	for _, out := range history {
+		rows = append(rows, HistoryRow{
+			Date:       out.Date,
+			Total:      out.Total,
+			Savings:    out.Savings,
+			StocksSum:  out.Stocks.Sum,
+			StocksDiff: out.Stocks.Diff,
+			AssetsSum:  out.Assets.Sum,
+			AssetsDiff: out.Assets.Diff,
+			CryptoSum:  out.Crypto.Sum,
+			CryptoDiff: out.Crypto.Diff,
+		})
+	}
+
+	return rows
+}
+
+func printHistoryTable(history []Out) {
+	rows := getHistoryRows(history)
+	table := newTable()
+	table.SetHeader([]string{"Date", "Total", "Savings", "Stocks Sum", "Stocks Diff", "Assets Sum", "Assets Diff", "Crypto Sum", "Crypto Diff"})
+
+	for _, row := range rows {
+		table.Append([]string{
+			row.Date.Local().Format("2006-01-02 15:04"),
+			fmt.Sprintf("%.2f", row.Total),
+			fmt.Sprintf("%.2f", row.Savings),
+			fmt.Sprintf("%.2f", row.StocksSum),
+			fmt.Sprintf("%.2f", row.StocksDiff),
+			fmt.Sprintf("%.2f", row.AssetsSum),
+			fmt.Sprintf("%.2f", row.AssetsDiff),
+			fmt.Sprintf("%.2f", row.CryptoSum),
+			fmt.Sprintf("%.2f", row.CryptoDiff),
+		})
+	}
+
+	table.Render(rows)
+}
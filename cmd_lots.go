@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"sort"
+	"time"
+)
+
+func cmdLots() *cli.Command {
+	return &cli.Command{
+		Name:  "lots",
+		Usage: "Print open tax lots",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return lots(c.String("file"))
+		},
+	}
+}
+
+func lots(file string) error {
+	c, _, err := loadConf(file)
+	if err != nil {
+		return fmt.Errorf("Could not read config file: %v", err)
+	}
+
+	quotes := fetchConfQuotes(c)
+	stockStats, assetsStats, cryptoStats, err := getConfStats(c, quotes, onQuoteErrorStale)
+	if err != nil {
+		return err
+	}
+
+	details := []InvestmentDetail{}
+	for _, stats := range []InvestmentStats{stockStats, assetsStats, cryptoStats} {
+		for _, values := range stats.Details {
+			for _, detail := range values {
+				if detail.Units > 0 {
+					details = append(details, detail)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(details, func(i, j int) bool {
+		if details[i].Quote.Symbol == details[j].Quote.Symbol {
+			return details[i].Date.Before(details[j].Date)
+		}
+
+		return details[i].Quote.Symbol < details[j].Quote.Symbol
+	})
+
+	printLotsTable(details, c, time.Now())
+	return nil
+}
+
+func printLotsTable(details []InvestmentDetail, c *Conf, now time.Time) {
+	table := newTable()
+	table.SetHeader([]string{"Symbol", "Method", "Date", "Held", "Term", "Units", "In", "Sum", "Unrealized"})
+
+	for _, detail := range details {
+		date, held, term := "", "", ""
+		if !detail.Date.IsZero() {
+			date = detail.Date.Format("2006-01-02")
+			held = fmt.Sprintf("%d days", holdingDays(detail.Date, now))
+			term = "short"
+			if isLongTerm(detail.Date, now) {
+				term = "long"
+			}
+		}
+
+		row := []string{
+			detail.Quote.Symbol,
+			c.costBasis(detail.Quote.Symbol),
+			date,
+			held,
+			term,
+			formatUnits(detail.Units),
+			fmt.Sprintf("%.2f", detail.In),
+			fmt.Sprintf("%.2f", detail.Sum),
+			fmt.Sprintf("%.2f", detail.Diff),
+		}
+
+		color := tablewriter.FgGreenColor
+		if detail.Diff < 0 {
+			color = tablewriter.FgRedColor
+		}
+
+		table.Rich(row, []tablewriter.Colors{
+			{},
+			{},
+			{},
+			{},
+			{},
+			{},
+			{},
+			{},
+			{tablewriter.Bold, color},
+		})
+	}
+
+	table.Render(details)
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
 	"log"
-	"os"
 	"sort"
 	"time"
 )
@@ -27,24 +26,50 @@ func cmdPortfolio() *cli.Command {
 				Value:   false,
 				Usage:   "watch mode",
 			},
+			&cli.StringFlag{
+				Name:  "on-quote-error",
+				Value: onQuoteErrorStale,
+				Usage: "fail, skip or stale",
+			},
+			&cli.BoolFlag{
+				Name:  "returns",
+				Value: false,
+				Usage: "only print the returns per symbol",
+			},
 		},
 		Action: func(c *cli.Context) error {
-			portfolio(c.String("file"), c.Bool("watch"))
-			return nil
+			policy := c.String("on-quote-error")
+			err := checkQuoteErrorPolicy(policy)
+			if err != nil {
+				return err
+			}
+
+			return portfolio(c.String("file"), c.Bool("watch"), policy, c.Bool("returns"))
 		},
 	}
 }
 
-func printInvestmentDetailsTable(details []InvestmentDetail) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Symbol", "Sum", "In", "Diff", "Quote Price", "Quote Pct"})
+// printInvestmentDetailsTable prints the lots, with the sums labeled as USD
+// if the output currency is another.
+func printInvestmentDetailsTable(details []InvestmentDetail, currency string) {
+	usd := usdLabel(currency)
+	table := newTable()
+	table.SetHeader([]string{"Symbol", "Date", "Units", "Sum" + usd, "In" + usd, "Diff" + usd, "Realized" + usd, "Quote Price", "Quote Pct"})
 
 	for _, detail := range details {
+		date := ""
+		if !detail.Date.IsZero() {
+			date = detail.Date.Format("2006-01-02")
+		}
+
 		row := []string{
 			detail.Quote.Symbol,
+			date,
+			formatUnits(detail.Units),
 			fmt.Sprintf("%.2f", detail.Sum),
 			fmt.Sprintf("%.2f", detail.In),
 			fmt.Sprintf("%.2f", detail.Diff),
+			fmt.Sprintf("%.2f", detail.Realized),
 			formatPrice(detail.Quote.Price),
 			fmt.Sprintf("%.2f", detail.Quote.Pct),
 		}
@@ -60,30 +85,137 @@ func printInvestmentDetailsTable(details []InvestmentDetail) {
 			{},
 			{},
 			{},
+			{},
+			{},
+			{},
 			{tablewriter.Bold, color},
 		})
 	}
 
-	table.Render()
+	table.Render(details)
+}
+
+// SymbolReturn is the total return of a symbol, the price diff of the open
+// lots, the realized gain and the dividends.
+type SymbolReturn struct {
+	Symbol   string
+	Diff     float64
+	Realized float64
+	Income   float64
+	Return   float64
+}
+
+// Portfolio is the lots and returns of all investments. Their sums are in
+// Currency, which is always USD.
+type Portfolio struct {
+	Currency string
+	Details  []InvestmentDetail
+	Returns  []SymbolReturn
+}
+
+func getReturns(details []InvestmentDetail, c *Conf, quotes QuoteSet) []SymbolReturn {
+	returns := []SymbolReturn{}
+	index := make(map[string]int)
+
+	for _, detail := range details {
+		symbol := detail.Quote.Symbol
+		i, ok := index[symbol]
+		if !ok {
+			i = len(returns)
+			index[symbol] = i
+			returns = append(returns, SymbolReturn{
+				Symbol: symbol,
+				Income: paymentsSum(c.Investments.Dividends[symbol], quotes.paymentFactor(symbol)),
+			})
+		}
+
+		returns[i].Diff = returns[i].Diff + detail.Diff
+		returns[i].Realized = returns[i].Realized + detail.Realized
+	}
+
+	for i, r := range returns {
+		returns[i].Return = r.Diff + r.Realized + r.Income
+	}
+
+	return returns
 }
 
-func printPortiflio(file string, clear bool) {
-	filename, err := findConfigFile(file)
+func printReturnsTable(returns []SymbolReturn, currency string) {
+	usd := usdLabel(currency)
+	table := newTable()
+	table.SetHeader([]string{"Symbol", "Diff" + usd, "Realized" + usd, "Income" + usd, "Return" + usd})
+
+	for _, r := range returns {
+		row := []string{
+			r.Symbol,
+			fmt.Sprintf("%.2f", r.Diff),
+			fmt.Sprintf("%.2f", r.Realized),
+			fmt.Sprintf("%.2f", r.Income),
+			fmt.Sprintf("%.2f", r.Return),
+		}
+
+		color := tablewriter.FgGreenColor
+		if r.Return < 0 {
+			color = tablewriter.FgRedColor
+		}
+
+		table.Rich(row, []tablewriter.Colors{
+			{},
+			{},
+			{},
+			{},
+			{tablewriter.Bold, color},
+		})
+	}
+
+	table.Render(returns)
+}
 
+// printPortiflio prints the lots and the returns, or only the returns. CSV
+// only has room for one table, so it gets the lots without the returns.
+func printPortiflio(file string, clear bool, policy string, returns bool) error {
+	c, _, err := loadConf(file)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("Could not read config file: %v", err)
 	}
 
-	c := &Conf{}
-	err = readYaml(filename, c)
+	quotes := fetchConfQuotes(c)
+	p, skipped, err := getPortfolio(c, quotes, policy)
 	if err != nil {
-		log.Fatal("Could not read config file: ", err)
+		return err
 	}
 
+	if clear {
+		clearScreen()
+	}
+
+	if isStructuredOutput() {
+		printData(p)
+	} else if returns {
+		printReturnsTable(p.Returns, c.outputCurrency())
+	} else {
+		printInvestmentDetailsTable(p.Details, c.outputCurrency())
+		if outputFormat != outputCSV {
+			printReturnsTable(p.Returns, c.outputCurrency())
+		}
+	}
+
+	printStale(quotes)
+	if len(skipped) > 0 {
+		printQuoteErrors(skipped)
+	}
+
+	return nil
+}
+
+// getPortfolio returns the lots of all investments sorted by symbol and the
+// returns per symbol. Missing quotes are handled like in getSum.
+func getPortfolio(c *Conf, quotes QuoteSet, policy string) (Portfolio, QuoteErrors, error) {
 	details := []InvestmentDetail{}
-	stockStats := getInvestmentsStats(c.Investments.Stocks)
-	assetsStats := getInvestmentsStats(c.Investments.Assets)
-	cryptoStats := getInvestmentsStats(c.Investments.Crypto)
+	stockStats, assetsStats, cryptoStats, quoteErr := getConfStats(c, quotes, policy)
+	if quoteErr != nil && policy != onQuoteErrorSkip {
+		return Portfolio{}, nil, quoteErr
+	}
 
 	for _, values := range stockStats.Details {
 		details = append(details, values...)
@@ -97,24 +229,24 @@ func printPortiflio(file string, clear bool) {
 		details = append(details, values...)
 	}
 
-	sort.Slice(details, func(i, j int) bool {
+	sort.SliceStable(details, func(i, j int) bool {
 		return details[i].Quote.Symbol > details[j].Quote.Symbol
 	})
 
-	if clear {
-		fmt.Print("\033[H\033[2J")
-	}
-
-	printInvestmentDetailsTable(details)
+	skipped, _ := quoteErr.(QuoteErrors)
+	return Portfolio{"USD", details, getReturns(details, c, quotes)}, skipped, nil
 }
 
-func portfolio(file string, watch bool) {
+func portfolio(file string, watch bool, policy string, returns bool) error {
 	if watch {
 		ticker := time.NewTicker(10 * time.Second)
 		for ; true; <-ticker.C {
-			printPortiflio(file, true)
+			err := printPortiflio(file, true, policy, returns)
+			if err != nil {
+				log.Println("Error: ", err)
+			}
 		}
 	}
 
-	printPortiflio(file, false)
+	return printPortiflio(file, false, policy, returns)
 }
@@ -6,7 +6,6 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
 	"math"
-	"os"
 	"strings"
 	"time"
 )
@@ -26,6 +25,11 @@ func cmdQuote() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			err := loadProvider()
+			if err != nil {
+				return err
+			}
+
 			symbols := []string{}
 			if c.NArg() > 0 {
 				symbols = strings.Split(c.Args().Get(0), ",")
@@ -38,23 +42,21 @@ func cmdQuote() *cli.Command {
 }
 
 func printQuotes(quotes []Quote) {
-	table := tablewriter.NewWriter(os.Stdout)
+	table := newTable()
 	headers := []string{"Symbol", "Price", "Pct", "State", "Name", "Trading Hours"}
 
 	for _, q := range quotes {
 		color := tablewriter.FgGreenColor
 		event := ""
 
-		if (q.State == "CLOSED" || q.State == "PREPRE") && q.MarketInfo.DurationUntilOpenPre != nil {
-			event = fmt.Sprintf("Pre market opens in %s", formatDuration(*q.MarketInfo.DurationUntilOpenPre))
-		} else if (q.State == "CLOSED" || q.State == "PREPRE") && q.MarketInfo.DurationUntilOpen != nil {
-			event = fmt.Sprintf("Market opens in %s", formatDuration(*q.MarketInfo.DurationUntilOpen))
-		} else if q.State == "PRE" && q.MarketInfo.DurationUntilOpen != nil {
-			event = fmt.Sprintf("Market opens in %s", formatDuration(*q.MarketInfo.DurationUntilOpen))
-		} else if q.State == "REGULAR" && q.MarketInfo.DurationUntilClose != nil {
-			event = fmt.Sprintf("Market closes in %s", formatDuration(*q.MarketInfo.DurationUntilClose))
-		} else if q.State == "POST" && q.MarketInfo.DurationUntilClosePost != nil {
-			event = fmt.Sprintf("Post market closes in %s", formatDuration(*q.MarketInfo.DurationUntilClosePost))
+		if q.StaleSince != nil {
+			event = formatStale(q)
+		} else if q.MarketInfo.Session != nil {
+			event = formatSession(*q.MarketInfo.Session)
+		}
+
+		if h := q.MarketInfo.Holiday; h != nil && h.CloseAt == "" && q.StaleSince == nil && event != "" {
+			event = h.Name + ", " + strings.ToLower(event[:1]) + event[1:]
 		}
 
 
@@ -80,7 +82,7 @@ func printQuotes(quotes []Quote) {
 
 	table.SetHeader(headers)
 
-	table.Render()
+	table.Render(quotes)
 }
 
 func printQuoteGraph(q Quote) {
@@ -99,22 +101,21 @@ func quoteInfo(symbols []string, watch bool) {
 	if watch {
 		ticker := time.NewTicker(2 * time.Second)
 		for ; true; <-ticker.C {
-			quotes := []Quote{}
-			for _, symbol := range symbols {
-				q, err := getQuote(symbol, true)
-				if err == nil {
-					quotes = append(quotes, q)
-				}
-			}
-
-			fmt.Print("\033[H\033[2J")
+			quotes := getQuotes(symbols)
+			clearScreen()
 			printQuotes(quotes)
-			if len(quotes) == 1 && quotes[0].State != "CLOSED" {
+			if len(quotes) == 1 && quotes[0].State != string(SessionClosed) && outputFormat == outputTable {
 				printQuoteGraph(quotes[0])
 			}
 		}
 	}
 
+	printQuotes(getQuotes(symbols))
+}
+
+// getQuotes returns the quotes of the symbols in order. Symbols which could
+// not be fetched are left out.
+func getQuotes(symbols []string) []Quote {
 	quotes := []Quote{}
 	for _, symbol := range symbols {
 		q, err := getQuote(symbol, true)
@@ -123,7 +124,27 @@ func quoteInfo(symbols []string, watch bool) {
 		}
 	}
 
-	printQuotes(quotes)
+	flushCache()
+	return quotes
+}
+
+// formatSession describes the next transition of a session.
+func formatSession(s Session) string {
+	until := formatDuration(time.Until(s.End))
+	switch s.State {
+	case SessionPre:
+		return "Market opens in " + until
+	case SessionRegular:
+		return "Market closes in " + until
+	case SessionPost:
+		return "Post market closes in " + until
+	}
+
+	if s.Next().State == SessionPre {
+		return "Pre market opens in " + until
+	}
+
+	return "Market opens in " + until
 }
 
 func formatDuration(d time.Duration) string {
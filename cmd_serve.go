@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Server ...
+type Server struct {
+	conf    *Conf
+	file    string
+	policy  string
+	metrics *Metrics
+	events  *Events
+}
+
+func cmdServe() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve a dashboard, the stats as JSON API and Prometheus metrics",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+			&cli.StringFlag{
+				Name:  "addr",
+				Value: "localhost:8080",
+				Usage: "listen address, like :8080 for all interfaces",
+			},
+			&cli.StringFlag{
+				Name:  "on-quote-error",
+				Value: onQuoteErrorStale,
+				Usage: "fail, skip or stale",
+			},
+			&cli.DurationFlag{
+				Name:  "refresh",
+				Value: time.Minute,
+				Usage: "refresh interval of the metrics and dashboard",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := checkQuoteErrorPolicy(c.String("on-quote-error"))
+			if err != nil {
+				return err
+			}
+
+			err = checkRefresh(c.Duration("refresh"))
+			if err != nil {
+				return err
+			}
+
+			conf, filename, err := loadConf(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			useSharedCache(conf.Cache, filename)
+			s := &Server{
+				conf:    conf,
+				file:    filename,
+				policy:  c.String("on-quote-error"),
+				metrics: &Metrics{},
+				events:  newEvents(),
+			}
+
+			go s.run(c.Duration("refresh"))
+			server := &http.Server{
+				Addr:              c.String("addr"),
+				Handler:           s.routes(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			log.Printf("Listening on %s", server.Addr)
+			return server.ListenAndServe()
+		},
+	}
+}
+
+func checkRefresh(refresh time.Duration) error {
+	if refresh <= 0 {
+		return fmt.Errorf("Invalid refresh %v, must be positive", refresh)
+	}
+
+	return nil
+}
+
+// run computes the stats every interval for the metrics and the dashboard.
+// On error the stats of the last refresh are kept.
+func (s *Server) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for ; true; <-ticker.C {
+		out, _, err := getSum(s.conf, fetchConfQuotes(s.conf), s.policy, time.Now())
+		if err != nil {
+			log.Println("Could not refresh stats:", err)
+			continue
+		}
+
+		s.metrics.update(out)
+		s.events.publish(out)
+	}
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", dashboardHandler())
+	mux.Handle("/api/events", s.events)
+	mux.HandleFunc("/api/sum", s.handleSum)
+	mux.HandleFunc("/api/portfolio", s.handlePortfolio)
+	mux.HandleFunc("/api/quote/", s.handleQuote)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/chart/", s.handleChart)
+	mux.Handle("/metrics", s.metrics)
+	return mux
+}
+
+func (s *Server) handleSum(w http.ResponseWriter, r *http.Request) {
+	out, _, err := getSum(s.conf, fetchConfQuotes(s.conf), s.policy, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, out)
+}
+
+func (s *Server) handlePortfolio(w http.ResponseWriter, r *http.Request) {
+	p, _, err := getPortfolio(s.conf, fetchConfQuotes(s.conf), s.policy)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, p)
+}
+
+// handleQuote serves /api/quote/{symbols} with a comma list of symbols.
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	symbols := strings.TrimPrefix(r.URL.Path, "/api/quote/")
+	if symbols == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("No symbols"))
+		return
+	}
+
+	writeJSON(w, getQuotes(strings.Split(symbols, ",")))
+}
+
+// handleHistory serves the snapshots with the query params from, to, last and
+// interval of the history command.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query, err := parseHistoryQuery(params.Get("from"), params.Get("to"), params.Get("last"), params.Get("interval"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, getHistoryRows(queryHistory(loadHistory(s.conf, s.file), query)))
+}
+
+// handleChart serves /api/chart/{symbol} with the query param period of the
+// graph command.
+func (s *Server) handleChart(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/api/chart/")
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("No symbol"))
+		return
+	}
+
+	bars, err := getChart(symbol, r.URL.Query().Get("period"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, bars)
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -3,18 +3,22 @@ package main
 import (
 	"fmt"
 	"github.com/guptarohit/asciigraph"
-	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
-	"io/ioutil"
+	"gopkg.in/yaml.v2"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Out ...
 type Out struct {
+	Version        int `yaml:"version,omitempty"`
 	Date           time.Time
+	Currency       string `yaml:",omitempty"`
 	Savings        float64
+	Interest       float64
 	Stocks         InvestmentStats `yaml:"stocks,omitempty"`
 	Assets         InvestmentStats `yaml:"assets,omitempty"`
 	Crypto         InvestmentStats `yaml:"crypto,omitempty"`
@@ -33,6 +37,8 @@ type Options struct {
 	NoSummary bool
 	NoGraph   bool
 	Graph     string
+
+	OnQuoteError string
 }
 
 func cmdSum() *cli.Command {
@@ -65,61 +71,115 @@ func cmdSum() *cli.Command {
 			&cli.StringFlag{
 				Name:  "graph",
 				Value: "total",
-				Usage: "Graph value",
+				Usage: "Graph value, any field like savings, stocks.sum or a comma list",
+			},
+			&cli.StringFlag{
+				Name:  "on-quote-error",
+				Value: onQuoteErrorStale,
+				Usage: "fail, skip or stale. Snapshots are only written if all quotes are available",
 			},
 		},
 		Action: func(c *cli.Context) error {
 			options := Options{
-				File:      c.String("file"),
-				Watch:     c.Bool("watch"),
-				NoSummary: c.Bool("no-summary"),
-				Graph:     "total",
+				File:         c.String("file"),
+				Watch:        c.Bool("watch"),
+				NoSummary:    c.Bool("no-summary"),
+				NoGraph:      c.Bool("no-graph"),
+				Graph:        c.String("graph"),
+				OnQuoteError: c.String("on-quote-error"),
+			}
+
+			err := checkQuoteErrorPolicy(options.OnQuoteError)
+			if err != nil {
+				return err
 			}
 
-			sum(options)
-			return nil
+			return sum(options)
 		},
 	}
 }
 
-func doSum(options Options) {
+func doSum(options Options) error {
 	start := time.Now()
-	filename, err := findConfigFile(options.File)
-
+	c, filename, err := loadConf(options.File)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	c := &Conf{}
-	err = readYaml(filename, c)
+	quotes := fetchConfQuotes(c)
+	out, skipped, err := getSum(c, quotes, options.OnQuoteError, start)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if !options.NoSummary {
+		printSumTable(out, foreignAmountRows(c, "Savings", c.Savings, quotes))
+		printStale(quotes)
+		if len(skipped) > 0 {
+			printQuoteErrors(skipped)
+			fmt.Fprintln(notes(), "Snapshot not written, skipped symbols are missing")
+		}
+		fmt.Fprintln(notes(), "")
+	}
+
+	history := loadHistory(c, filename)
+	if !options.NoSummary && len(skipped) == 0 && shouldWriteSnapshot(out, history, c.Snapshot) {
+		err = writeSnapshot(out, c, filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !options.NoGraph && len(history) > 0 && outputFormat == outputTable {
+		return printSumGraph(append(history, out), strings.Split(options.Graph, ","))
+	}
+
+	return nil
+}
+
+// getSum computes the stats of a config at date. With the skip policy the
+// symbols without quote are left out and returned as QuoteErrors, with the
+// other policies missing quotes are an error.
+func getSum(c *Conf, quotes QuoteSet, policy string, date time.Time) (Out, QuoteErrors, error) {
+	stockStats, assetsStats, cryptoStats, quoteErr := getConfStats(c, quotes, policy)
+	if quoteErr != nil && policy != onQuoteErrorSkip {
+		return Out{}, nil, quoteErr
 	}
 
-	savings := 0.0
+	skipped, _ := quoteErr.(QuoteErrors)
+	if err := quotes.checkRate(c.Currency, policy); err != nil {
+		return Out{}, nil, err
+	}
+
+	currencyFactor := quotes.currencyFactor(c.Currency)
+
+	investmentsSum := assetsStats.Sum + stockStats.Sum + cryptoStats.Sum
+
 	income := 0.0
 	expenses := 0.0
-	currencyFactor := getCurrency(c.Currency)
-	stockStats := getInvestmentsStats(c.Investments.Stocks)
-	assetsStats := getInvestmentsStats(c.Investments.Assets)
-	cryptoStats := getInvestmentsStats(c.Investments.Crypto)
-	investmentsSum := assetsStats.Sum + stockStats.Sum + cryptoStats.Sum
+	interest := 0.0
+	savings, err := amountsSum(c, c.Savings, quotes, policy)
+	if err == nil {
+		income, err = amountsSum(c, c.Income, quotes, policy)
+	}
 
-	for _, value := range c.Savings {
-		savings = savings + value
+	if err == nil {
+		expenses, err = amountsSum(c, c.Expenses, quotes, policy)
 	}
 
-	for _, value := range c.Income {
-		income = income + value
+	if err == nil {
+		interest, err = interestSum(c, quotes, policy)
 	}
 
-	for _, value := range c.Expenses {
-		expenses = expenses + value
+	if err != nil {
+		return Out{}, nil, err
 	}
 
 	out := Out{
-		Date:           start,
+		Date:           date,
+		Currency:       c.outputCurrency(),
 		Savings:        savings,
+		Interest:       interest,
 		Stocks:         stockStats,
 		Assets:         assetsStats,
 		Crypto:         cryptoStats,
@@ -130,134 +190,212 @@ func doSum(options Options) {
 		Budget:         income - expenses,
 	}
 
-	if !options.NoSummary {
-		printSumTable(out, options)
-		fmt.Println("")
+	return out, skipped, nil
+}
+
+// printSumGraph plots one or more fields of the history overlaid. Snapshots
+// without a field, like from before a category existed, plot it as 0.
+func printSumGraph(history []Out, names []string) error {
+	allowed := graphValueNames()
+	known := make(map[string]bool)
+	for _, name := range allowed {
+		known[name] = true
 	}
 
-	history := loadHistory(filename)
-	if !options.NoSummary {
-		writeFile(out, filename, start)
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if !known[names[i]] {
+			return fmt.Errorf("Unknown graph value %q, allowed: %s", names[i], strings.Join(allowed, ","))
+		}
+	}
+
+	colors := []asciigraph.AnsiColor{
+		asciigraph.Default,
+		asciigraph.Green,
+		asciigraph.Red,
+		asciigraph.Blue,
+		asciigraph.Yellow,
+		asciigraph.Magenta,
+		asciigraph.Cyan,
+	}
+
+	data := make([][]float64, len(names))
+	for _, out := range history {
+		values := outValues(out)
+		for i, name := range names {
+			data[i] = append(data[i], values[name])
+		}
+	}
+
+	options := []asciigraph.Option{asciigraph.Height(8)}
+	if len(data[0]) > 80 {
+		options = append(options, asciigraph.Width(80))
+	}
+
+	legend := []string{}
+	seriesColors := []asciigraph.AnsiColor{}
+	for i, name := range names {
+		color := colors[i%len(colors)]
+		seriesColors = append(seriesColors, color)
+		if isTerminal(os.Stdout) && len(names) > 1 {
+			legend = append(legend, fmt.Sprintf("%s■%s %s", color, asciigraph.Default, name))
+		} else {
+			legend = append(legend, name)
+		}
+	}
+
+	if isTerminal(os.Stdout) {
+		options = append(options, asciigraph.SeriesColors(seriesColors...))
 	}
 
-	if !options.NoGraph && len(history) > 0 {
-		data := []float64{}
+	fmt.Println(strings.Join(legend, "  ") + ":")
+	fmt.Println(asciigraph.PlotMany(data, options...))
+	return nil
+}
 
-		for _, stat := range append(history, out) {
-			data = append(data, stat.Total)
+// outValues flattens the number fields of an Out by their yaml names, like
+// total or stocks.sum. The version and symbol details are left out.
+func outValues(out Out) map[string]float64 {
+	values := make(map[string]float64)
+	fields := make(map[string]interface{})
+	yaml.Unmarshal(yamlToBytes(&out), &fields)
+
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		switch v := value.(type) {
+		case int:
+			values[prefix] = float64(v)
+		case float64:
+			values[prefix] = v
+		case map[interface{}]interface{}:
+			for key, field := range v {
+				if key != "symbols" {
+					flatten(fmt.Sprintf("%s.%v", prefix, key), field)
+				}
+			}
 		}
+	}
 
-		graph := asciigraph.Plot(data, asciigraph.Height(8))
-		if len(data) > 80 {
-			graph = asciigraph.Plot(data, asciigraph.Height(8), asciigraph.Width(80))
+	for key, field := range fields {
+		if key != "version" {
+			flatten(key, field)
 		}
+	}
 
-		fmt.Println("Total:")
-		fmt.Println(graph)
+	return values
+}
+
+// graphValueNames returns the names of all values of a snapshot, including
+// the categories left out of snapshots without investments in them.
+func graphValueNames() []string {
+	out := Out{}
+	out.Stocks.Sum = 1
+	out.Assets.Sum = 1
+	out.Crypto.Sum = 1
+	return outValueNames(outValues(out))
+}
+
+func outValueNames(values map[string]float64) []string {
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
 	}
+
+	sort.Strings(names)
+	return names
 }
 
-func sum(options Options) {
+func sum(options Options) error {
 	if options.Watch {
 		ticker := time.NewTicker(10 * time.Second)
 		for ; true; <-ticker.C {
-			fmt.Print("\033[H\033[2J")
-			doSum(options)
+			clearScreen()
+			err := doSum(options)
+			if err != nil {
+				log.Println("Error: ", err)
+			}
 		}
 	}
 
-	doSum(options)
+	return doSum(options)
 }
 
-func printSumTable(out Out, options Options) {
+// printSumTable prints the accounts rows in another currency below the
+// savings.
+func printSumTable(out Out, accounts [][]string) {
 	data := [][]string{}
+	usd := usdLabel(out.Currency)
+	own := ""
+	if usd != "" {
+		own = " (" + out.Currency + ")"
+	}
 
 	data = [][]string{
-		{"Savings", fmt.Sprintf("%.2f", out.Savings)},
+		{"Savings" + own, fmt.Sprintf("%.2f", out.Savings)},
+	}
+
+	data = append(data, accounts...)
+
+	if out.Interest != 0 {
+		data = append(data, []string{"Savings Interest" + own, fmt.Sprintf("%.2f", out.Interest)})
 	}
 
-	if out.Stocks.Sum > 0 {
+	if out.Stocks.Sum > 0 || out.Stocks.Realized != 0 || out.Stocks.Income != 0 {
 		data = append(data, [][]string{
-			{"Stocks Sum", fmt.Sprintf("%.2f", out.Stocks.Sum)},
-			{"Stocks In", fmt.Sprintf("%.2f", out.Stocks.In)},
-			{"Stocks Diff", fmt.Sprintf("%.2f", out.Stocks.Diff)},
-			{"Stocks Loss", fmt.Sprintf("%.2f", out.Stocks.Loss)},
+			{"Stocks Sum" + usd, fmt.Sprintf("%.2f", out.Stocks.Sum)},
+			{"Stocks In" + usd, fmt.Sprintf("%.2f", out.Stocks.In)},
+			{"Stocks Diff" + usd, fmt.Sprintf("%.2f", out.Stocks.Diff)},
+			{"Stocks Loss" + usd, fmt.Sprintf("%.2f", out.Stocks.Loss)},
+			{"Stocks Realized" + usd, fmt.Sprintf("%.2f", out.Stocks.Realized)},
+			{"Stocks Income" + usd, fmt.Sprintf("%.2f", out.Stocks.Income)},
+			{"Stocks Return" + usd, fmt.Sprintf("%.2f", out.Stocks.totalReturn())},
 		}...)
 	}
 
-	if out.Assets.Sum > 0 {
+	if out.Assets.Sum > 0 || out.Assets.Realized != 0 || out.Assets.Income != 0 {
 		data = append(data, [][]string{
-			{"Assets Sum", fmt.Sprintf("%.2f", out.Assets.Sum)},
-			{"Assets In", fmt.Sprintf("%.2f", out.Assets.In)},
-			{"Assets Diff", fmt.Sprintf("%.2f", out.Assets.Diff)},
-			{"Assets Loss", fmt.Sprintf("%.2f", out.Assets.Loss)},
+			{"Assets Sum" + usd, fmt.Sprintf("%.2f", out.Assets.Sum)},
+			{"Assets In" + usd, fmt.Sprintf("%.2f", out.Assets.In)},
+			{"Assets Diff" + usd, fmt.Sprintf("%.2f", out.Assets.Diff)},
+			{"Assets Loss" + usd, fmt.Sprintf("%.2f", out.Assets.Loss)},
+			{"Assets Realized" + usd, fmt.Sprintf("%.2f", out.Assets.Realized)},
+			{"Assets Income" + usd, fmt.Sprintf("%.2f", out.Assets.Income)},
+			{"Assets Return" + usd, fmt.Sprintf("%.2f", out.Assets.totalReturn())},
 		}...)
 	}
 
-	if out.Crypto.Sum > 0 {
+	if out.Crypto.Sum > 0 || out.Crypto.Realized != 0 || out.Crypto.Income != 0 {
 		data = append(data, [][]string{
-			{"Crypto Sum", fmt.Sprintf("%.2f", out.Crypto.Sum)},
-			{"Crypto In", fmt.Sprintf("%.2f", out.Crypto.In)},
-			{"Crypto Diff", fmt.Sprintf("%.2f", out.Crypto.Diff)},
-			{"Crypto Loss", fmt.Sprintf("%.2f", out.Crypto.Loss)},
+			{"Crypto Sum" + usd, fmt.Sprintf("%.2f", out.Crypto.Sum)},
+			{"Crypto In" + usd, fmt.Sprintf("%.2f", out.Crypto.In)},
+			{"Crypto Diff" + usd, fmt.Sprintf("%.2f", out.Crypto.Diff)},
+			{"Crypto Loss" + usd, fmt.Sprintf("%.2f", out.Crypto.Loss)},
+			{"Crypto Realized" + usd, fmt.Sprintf("%.2f", out.Crypto.Realized)},
+			{"Crypto Income" + usd, fmt.Sprintf("%.2f", out.Crypto.Income)},
+			{"Crypto Return" + usd, fmt.Sprintf("%.2f", out.Crypto.totalReturn())},
 		}...)
 	}
 
 	data = append(data, [][]string{
-		{"Investments Sum", fmt.Sprintf("%.2f", out.InvestmentsSum)},
-		{"Total", fmt.Sprintf("%.2f", out.Total)},
+		{"Investments Sum" + usd, fmt.Sprintf("%.2f", out.InvestmentsSum)},
+		{"Total" + own, fmt.Sprintf("%.2f", out.Total)},
 	}...)
 
-	table := tablewriter.NewWriter(os.Stdout)
+	table := newTable()
 
 	for _, v := range data {
 		table.Append(v)
 	}
 
-	table.Render()
+	table.Render(out)
 }
 
-func writeFile(out Out, configFile string, date time.Time) {
-	dir, err := getOutDir(configFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	target := dir + "/" + date.Format(time.RFC3339) + ".yaml"
-	file, err := os.Create(target)
-
-	if err != nil {
-		log.Fatal("Could not create file:", dir)
-	}
-
-	file.Write(yamlToBytes(&out))
-	file.Close()
-}
-
-func loadHistory(configFile string) []Out {
-	outs := make([]Out, 0)
-	dir, err := getOutDir(configFile)
-
-	if err != nil {
-		return outs
-	}
-
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return outs
-	}
-
-	for _, f := range files {
-		path := dir + "/" + f.Name()
-		out := &Out{}
-		err := readYaml(path, out)
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-
-		outs = append(outs, *out)
+// usdLabel labels the values of investments, which are in USD, if the output
+// currency is another.
+func usdLabel(currency string) string {
+	if currency == "" || currency == "USD" {
+		return ""
 	}
 
-	return outs
+	return " (USD)"
 }
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaxRow ...
+type TaxRow struct {
+	Symbol    string
+	ShortTerm float64 `yaml:"short_term"`
+	LongTerm  float64 `yaml:"long_term"`
+	// Fees are the buy and sell fees included in the gains.
+	Fees      float64
+	Dividends float64
+}
+
+// TaxReport ...
+type TaxReport struct {
+	Year     int
+	Currency string
+	Rows     []TaxRow
+	Total    TaxRow
+}
+
+func cmdTax() *cli.Command {
+	return &cli.Command{
+		Name:  "tax",
+		Usage: "Print realized gains and dividends of a year",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+			&cli.IntFlag{
+				Name:    "year",
+				Aliases: []string{"y"},
+				Value:   time.Now().Year(),
+				Usage:   "tax year",
+			},
+			&cli.BoolFlag{
+				Name:  "csv",
+				Value: false,
+				Usage: "print csv, same as --output csv",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Bool("csv") {
+				outputFormat = outputCSV
+			}
+
+			return tax(c.String("file"), c.Int("year"))
+		},
+	}
+}
+
+func tax(file string, year int) error {
+	c, _, err := loadConf(file)
+	if err != nil {
+		return fmt.Errorf("Could not read config file: %v", err)
+	}
+
+	rows, err := getTaxRows(c, year)
+	if err != nil {
+		return err
+	}
+
+	currency := c.outputCurrency()
+	fmt.Fprintf(notes(), "Tax report %d in %s\n", year, currency)
+	printTaxTable(TaxReport{year, currency, rows, taxTotal(rows)})
+	return nil
+}
+
+// getTaxRows matches the sales of all symbols to their lots and sums the
+// gains of sales in the year by holding period. Every order is converted to
+// the config currency at the rate of its date, like the dividends of the
+// year. Lots without date count as long term.
+func getTaxRows(c *Conf, year int) ([]TaxRow, error) {
+	currencies, err := quoteCurrencies(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]float64)
+	rateAt := func(currency string, date time.Time) (float64, error) {
+		key := currency + date.Format("2006-01-02")
+		if rate, ok := rates[key]; ok {
+			return rate, nil
+		}
+
+		rate, err := getCurrencyAt(currency, date)
+		if err == nil && rate == 0 {
+			err = fmt.Errorf("Could not find %s rate at %s", currency, date.Format("2006-01-02"))
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		rates[key] = rate
+		return rate, nil
+	}
+
+	// The first failed rate is returned after matching the lots.
+	var rateErr error
+	factorOf := func(symbol string, order Order) float64 {
+		currency := order.Currency
+		if currency == "" {
+			currency = currencies[symbol]
+		}
+
+		from, err := rateAt(currency, order.Date)
+		to := 0.0
+		if err == nil {
+			to, err = rateAt(c.Currency, order.Date)
+		}
+
+		if err != nil {
+			if rateErr == nil {
+				rateErr = err
+			}
+
+			return 1.0
+		}
+
+		return from / to
+	}
+
+	rows := []TaxRow{}
+	for _, investments := range c.investmentOrders() {
+		for symbol, orders := range investments {
+			symbol := symbol
+			factor := func(order Order) float64 {
+				return factorOf(symbol, order)
+			}
+
+			for _, order := range orders {
+				if order.Side == sideSell && order.Date.IsZero() {
+					return rows, fmt.Errorf("%s: sell order without date", symbol)
+				}
+			}
+
+			_, sales, _ := matchLots(orders, factor, c.costBasis(symbol))
+			if rateErr != nil {
+				return rows, rateErr
+			}
+
+			row := TaxRow{Symbol: symbol}
+			for _, sale := range sales {
+				if sale.Date.Year() != year {
+					continue
+				}
+
+				if sale.BoughtAt.IsZero() || isLongTerm(sale.BoughtAt, sale.Date) {
+					row.LongTerm = row.LongTerm + sale.Gain
+				} else {
+					row.ShortTerm = row.ShortTerm + sale.Gain
+				}
+
+				row.Fees = row.Fees + sale.Fees
+			}
+
+			for _, payment := range c.Investments.Dividends[symbol] {
+				if payment.Date.Year() == year {
+					order := Order{Date: payment.Date, Currency: payment.Currency}
+					row.Dividends = row.Dividends + payment.Amount/factor(order)
+				}
+			}
+
+			if rateErr != nil {
+				return rows, rateErr
+			}
+
+			if row != (TaxRow{Symbol: symbol}) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Symbol < rows[j].Symbol
+	})
+
+	return rows, nil
+}
+
+func taxTotal(rows []TaxRow) TaxRow {
+	total := TaxRow{Symbol: "Total"}
+	for _, row := range rows {
+		total.ShortTerm = total.ShortTerm + row.ShortTerm
+		total.LongTerm = total.LongTerm + row.LongTerm
+		total.Fees = total.Fees + row.Fees
+		total.Dividends = total.Dividends + row.Dividends
+	}
+
+	return total
+}
+
+func taxRecord(row TaxRow) []string {
+	return []string{
+		row.Symbol,
+		fmt.Sprintf("%.2f", row.ShortTerm),
+		fmt.Sprintf("%.2f", row.LongTerm),
+		fmt.Sprintf("%.2f", row.Fees),
+		fmt.Sprintf("%.2f", row.Dividends),
+	}
+}
+
+func printTaxTable(report TaxReport) {
+	table := newTable()
+	table.SetHeader([]string{"Symbol", "Short Term", "Long Term", "Fees (included in gains)", "Dividends"})
+
+	for _, row := range report.Rows {
+		table.Append(taxRecord(row))
+	}
+
+	table.SetFooter(taxRecord(report.Total))
+	table.Render(report)
+}
+
+// quoteCurrencies returns the quote currency of every symbol with an order
+// or dividend without currency, which are in the currency of the quote. It
+// fails if such a quote can not be fetched.
+func quoteCurrencies(c *Conf) (map[string]string, error) {
+	symbols := []string{}
+	for _, investments := range c.investmentOrders() {
+		for symbol, orders := range investments {
+			for _, order := range orders {
+				if order.Currency == "" {
+					symbols = append(symbols, symbol)
+				}
+			}
+
+			for _, payment := range c.Investments.Dividends[symbol] {
+				if payment.Currency == "" {
+					symbols = append(symbols, symbol)
+				}
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	currencies := make(map[string]string)
+	errs := QuoteErrors{}
+	jobs := []func(){}
+	for _, symbol := range unique(symbols) {
+		symbol := symbol
+		jobs = append(jobs, func() {
+			q, err := getQuote(symbol, true)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[symbol] = err
+				return
+			}
+
+			currencies[symbol] = q.Currency
+		})
+	}
+
+	runJobs(jobs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return currencies, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+// useRates serves the historical rates from an in-memory fx store.
+func useRates(t *testing.T, rates map[string]map[string]float64) {
+	prev := fxStore
+	fxStore = &FXStore{}
+	t.Cleanup(func() { fxStore = prev })
+
+	_, err := fxStore.add(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetTaxRows(t *testing.T) {
+	useFixtures(t, map[string]Quote{"AAPL": {Price: 170, Currency: "USD"}}, nil)
+	useRates(t, map[string]map[string]float64{
+		"EUR": {
+			"2020-01-02": 0.9,
+			"2021-05-03": 0.85,
+			"2022-01-03": 0.8,
+			"2022-02-01": 0.88,
+			"2022-03-01": 0.85,
+			"2022-06-01": 0.9,
+		},
+	})
+
+	c := &Conf{Currency: "EUR"}
+	c.Investments.Stocks = map[string][]Order{
+		"AAPL": {
+			{Date: date("2022-01-03"), Units: 2, Price: 100, Fee: 4},
+			{Date: date("2022-06-01"), Side: sideSell, Units: 1, Price: 150, Fee: 2},
+		},
+		"MSFT": {
+			{Date: date("2020-01-02"), Units: 2, Price: 50, Currency: "USD"},
+			{Date: date("2021-05-03"), Side: sideSell, Units: 1, Price: 40, Currency: "USD"},
+			{Date: date("2022-02-01"), Side: sideSell, Units: 1, Price: 60, Currency: "USD"},
+		},
+	}
+	c.Investments.Dividends = map[string][]Payment{
+		"AAPL": {{Date: date("2022-03-01"), Amount: 10}},
+	}
+
+	rows, err := getTaxRows(c, 2022)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Symbol != "AAPL" || rows[1].Symbol != "MSFT" {
+		t.Fatalf("rows = %+v, want AAPL and MSFT", rows)
+	}
+
+	// Half of the buy fee is in the cost of the sold unit, the other half
+	// stays with the open lot.
+	assertFloat(t, "AAPL short term", rows[0].ShortTerm, 135-1.8-81.6)
+	assertFloat(t, "AAPL fees", rows[0].Fees, 1.8+1.6)
+	assertFloat(t, "AAPL dividends", rows[0].Dividends, 8.5)
+	assertFloat(t, "MSFT long term", rows[1].LongTerm, 52.8-45)
+	assertFloat(t, "MSFT short term", rows[1].ShortTerm, 0)
+}
+
+func TestGetTaxRowsErrors(t *testing.T) {
+	useFixtures(t, map[string]Quote{}, nil)
+	useRates(t, map[string]map[string]float64{"EUR": {"2022-01-03": 0.8}})
+
+	c := &Conf{Currency: "EUR"}
+	c.Investments.Stocks = map[string][]Order{
+		"AAPL": {{Date: date("2022-01-03"), Units: 1, Price: 100}},
+	}
+
+	_, err := getTaxRows(c, 2022)
+	if _, ok := err.(QuoteErrors); !ok {
+		t.Errorf("err = %v, want a QuoteErrors for the currency of AAPL", err)
+	}
+
+	c.Investments.Stocks["AAPL"][0].Currency = "GBP"
+	_, err = getTaxRows(c, 2022)
+	if err == nil {
+		t.Error("got no error for a missing GBP rate")
+	}
+}
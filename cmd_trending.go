@@ -6,10 +6,17 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
 	"log"
-	"os"
 	"time"
 )
 
+// Trending ...
+type Trending struct {
+	Symbol string
+	Name   string
+	Price  float64
+	Pct    float64
+}
+
 func cmdTrending() *cli.Command {
 	return &cli.Command{
 		Name:  "trending",
@@ -29,6 +36,11 @@ func cmdTrending() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			err := loadProvider()
+			if err != nil {
+				return err
+			}
+
 			number := c.Int("number")
 			if number > 20 {
 				number = 20
@@ -43,7 +55,7 @@ func trending(watch bool, max int) {
 	if watch {
 		ticker := time.NewTicker(60 * time.Second)
 		for ; true; <-ticker.C {
-			fmt.Print("\033[H\033[2J")
+			clearScreen()
 			printTrending(max)
 		}
 	}
@@ -68,11 +80,13 @@ func printTrending(max int) {
 		log.Fatal("Could not decode API response: ", err)
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
+	table := newTable()
 	table.SetHeader([]string{"Symbol", "Name", "Price", "Pct"})
+	trending := []Trending{}
 
 	for _, tuple := range list[0:max] {
 		q, _ := getQuote(tuple[1], false)
+		trending = append(trending, Trending{tuple[1], tuple[0], q.Price, q.Pct})
 		row := []string{
 			tuple[1],
 			tuple[0],
@@ -93,6 +107,6 @@ func printTrending(max int) {
 		})
 	}
 
-	fmt.Println("")
-	table.Render()
+	fmt.Fprintln(notes(), "")
+	table.Render(trending)
 }
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"fmt"
+	"github.com/guptarohit/asciigraph"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Panes of the tui with selectable symbols.
+const (
+	paneHoldings = iota
+	paneWatchlist
+)
+
+// tuiPeriods are the chart periods of the graph command.
+var tuiPeriods = []string{"1d", "1wk", "2wk", "1mo", "6mo", "1yr", "5yr"}
+
+// Holding is the position of a symbol over all its lots.
+type Holding struct {
+	Symbol string
+	Units  float64
+	Sum    float64
+	Diff   float64
+	Quote  Quote
+}
+
+// tuiData is the result of one refresh.
+type tuiData struct {
+	sum      Out
+	holdings []Holding
+	quotes   []Quote
+	skipped  QuoteErrors
+	err      error
+	date     time.Time
+}
+
+type tuiChart struct {
+	symbol string
+	period string
+	bars   []Bar
+	err    error
+}
+
+type tuiState struct {
+	data     tuiData
+	chart    tuiChart
+	loading  bool
+	focus    int
+	selected [2]int
+	period   int
+	full     bool
+	width    int
+	height   int
+}
+
+func cmdTUI() *cli.Command {
+	return &cli.Command{
+		Name:  "tui",
+		Usage: "Full-screen terminal dashboard",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Value:   "",
+				Usage:   "finance config",
+			},
+			&cli.StringFlag{
+				Name:  "watchlist",
+				Value: "",
+				Usage: "comma list of symbols to quote, default are the symbols of the config",
+			},
+			&cli.DurationFlag{
+				Name:  "refresh",
+				Value: 10 * time.Second,
+				Usage: "refresh interval",
+			},
+			&cli.StringFlag{
+				Name:  "on-quote-error",
+				Value: onQuoteErrorStale,
+				Usage: "fail, skip or stale",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := checkQuoteErrorPolicy(c.String("on-quote-error"))
+			if err != nil {
+				return err
+			}
+
+			err = checkRefresh(c.Duration("refresh"))
+			if err != nil {
+				return err
+			}
+
+			if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+				return fmt.Errorf("tui needs a terminal")
+			}
+
+			conf, filename, err := loadConf(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			useSharedCache(conf.Cache, filename)
+			watchlist := confSymbols(conf)
+			if c.String("watchlist") != "" {
+				watchlist = strings.Split(c.String("watchlist"), ",")
+			}
+
+			return tui(conf, c.String("on-quote-error"), watchlist, c.Duration("refresh"))
+		},
+	}
+}
+
+func tui(conf *Conf, policy string, watchlist []string, refresh time.Duration) error {
+	fd := int(os.Stdin.Fd())
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+
+	// alternate screen and hidden cursor
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		term.Restore(fd, old)
+	}()
+
+	keys := make(chan string)
+	go readKeys(keys)
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	data := make(chan tuiData)
+	charts := make(chan tuiChart)
+	load := func() {
+		go func() { data <- loadTUIData(conf, policy, watchlist) }()
+	}
+
+	s := &tuiState{period: 3, loading: true}
+	s.width, s.height, _ = term.GetSize(int(os.Stdout.Fd()))
+	load()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		s.render()
+		select {
+		case <-ticker.C:
+			load()
+		case d := <-data:
+			s.data = d
+			s.loading = false
+			s.clampSelection()
+			s.loadChart(charts, true)
+		case ch := <-charts:
+			if ch.symbol == s.symbol() && ch.period == tuiPeriods[s.period] {
+				s.chart = ch
+			}
+		case <-resize:
+			s.width, s.height, _ = term.GetSize(int(os.Stdout.Fd()))
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+
+			switch key {
+			case "q", "\x03":
+				return nil
+			case "r":
+				load()
+			case "\t":
+				s.focus = (s.focus + 1) % 2
+				s.loadChart(charts, false)
+			case "\r", "g":
+				s.full = !s.full
+			case "\x1b":
+				s.full = false
+			case "\x1b[A", "k":
+				s.selected[s.focus]--
+				s.clampSelection()
+				s.loadChart(charts, false)
+			case "\x1b[B", "j":
+				s.selected[s.focus]++
+				s.clampSelection()
+				s.loadChart(charts, false)
+			case "\x1b[D", "h":
+				s.period = (s.period + len(tuiPeriods) - 1) % len(tuiPeriods)
+				s.loadChart(charts, false)
+			case "\x1b[C", "l":
+				s.period = (s.period + 1) % len(tuiPeriods)
+				s.loadChart(charts, false)
+			default:
+				if len(key) == 1 && key[0] >= '1' && int(key[0]-'1') < len(tuiPeriods) {
+					s.period = int(key[0] - '1')
+					s.loadChart(charts, false)
+				}
+			}
+		}
+	}
+}
+
+// readKeys sends every key press, an escape sequence like an arrow key is
+// one key.
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+
+		keys <- string(buf[:n])
+	}
+}
+
+// loadTUIData computes the sum, holdings and watchlist quotes. The quotes of
+// symbols in both come from the shared cache.
+func loadTUIData(conf *Conf, policy string, watchlist []string) tuiData {
+	quotes := fetchConfQuotes(conf)
+	d := tuiData{date: time.Now()}
+	d.sum, d.skipped, d.err = getSum(conf, quotes, policy, d.date)
+	if d.err != nil {
+		return d
+	}
+
+	p, _, err := getPortfolio(conf, quotes, policy)
+	if err != nil {
+		d.err = err
+		return d
+	}
+
+	d.holdings = getHoldings(p.Details)
+	d.quotes = getQuotes(watchlist)
+
+	return d
+}
+
+// getHoldings sums the lots of each symbol in the order of details.
+func getHoldings(details []InvestmentDetail) []Holding {
+	holdings := []Holding{}
+	index := make(map[string]int)
+	for _, d := range details {
+		i, ok := index[d.Quote.Symbol]
+		if !ok {
+			i = len(holdings)
+			index[d.Quote.Symbol] = i
+			holdings = append(holdings, Holding{Symbol: d.Quote.Symbol, Quote: d.Quote})
+		}
+
+		holdings[i].Units = holdings[i].Units + d.Units
+		holdings[i].Sum = holdings[i].Sum + d.Sum
+		holdings[i].Diff = holdings[i].Diff + d.Diff
+	}
+
+	return holdings
+}
+
+// symbol returns the selected symbol of the focused pane.
+func (s *tuiState) symbol() string {
+	i := s.selected[s.focus]
+	if s.focus == paneHoldings && i < len(s.data.holdings) {
+		return s.data.holdings[i].Symbol
+	}
+
+	if s.focus == paneWatchlist && i < len(s.data.quotes) {
+		return s.data.quotes[i].Symbol
+	}
+
+	return ""
+}
+
+func (s *tuiState) clampSelection() {
+	counts := [2]int{len(s.data.holdings), len(s.data.quotes)}
+	for pane, count := range counts {
+		if s.selected[pane] >= count {
+			s.selected[pane] = count - 1
+		}
+
+		if s.selected[pane] < 0 {
+			s.selected[pane] = 0
+		}
+	}
+}
+
+// loadChart fetches the chart of the selected symbol in the background if
+// it is not shown yet, or always with force.
+func (s *tuiState) loadChart(charts chan<- tuiChart, force bool) {
+	symbol := s.symbol()
+	period := tuiPeriods[s.period]
+	if symbol == "" || (!force && s.chart.symbol == symbol && s.chart.period == period) {
+		return
+	}
+
+	if s.chart.symbol != symbol || s.chart.period != period {
+		s.chart = tuiChart{symbol: symbol, period: period}
+	}
+
+	go func() {
+		bars, err := getChart(symbol, period)
+		charts <- tuiChart{symbol, period, bars, err}
+	}()
+}
+
+func (s *tuiState) render() {
+	lines := []string{s.titleLine()}
+	if s.full {
+		lines = append(lines, s.chartLines(s.height-len(lines))...)
+	} else {
+		lines = append(lines, joinColumns(s.summaryLines(), s.watchlistLines(), s.width/2)...)
+		lines = append(lines, "")
+		lines = append(lines, s.holdingLines(s.height/3)...)
+		lines = append(lines, "")
+		lines = append(lines, s.chartLines(s.height-len(lines))...)
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i := 0; i < s.height && i < len(lines); i++ {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+
+		b.WriteString(lines[i] + "\033[0m\033[K")
+	}
+
+	b.WriteString("\033[J")
+	fmt.Print(b.String())
+}
+
+func (s *tuiState) titleLine() string {
+	status := "loading..."
+	if !s.loading {
+		status = "updated " + s.data.date.Format("15:04:05")
+	}
+
+	if s.data.err != nil {
+		status = "error: " + s.data.err.Error()
+	} else if len(s.data.skipped) > 0 {
+		status = status + ", skipped " + strings.Join(s.data.skipped.symbols(), ",")
+	}
+
+	help := "q quit  tab pane  ↑↓ select  ←→ period  enter chart"
+	title := " fin-stats  " + status
+	return "\033[7m" + fit(title, s.width-utf8.RuneCountInString(help)-1) + help + " \033[0m"
+}
+
+func (s *tuiState) summaryLines() []string {
+	out := s.data.sum
+	title := "Summary"
+	if usdLabel(out.Currency) != "" {
+		title = "Summary in " + out.Currency + ", investments in USD"
+	}
+
+	lines := []string{header(title), row("Total", out.Total), row("Savings", out.Savings)}
+	for _, c := range []struct {
+		name  string
+		stats InvestmentStats
+	}{
+		{"Stocks", out.Stocks},
+		{"Assets", out.Assets},
+		{"Crypto", out.Crypto},
+	} {
+		if c.stats.Sum > 0 || c.stats.Realized != 0 {
+			lines = append(lines, row(c.name+" Sum", c.stats.Sum)+"  "+colored(fmt.Sprintf("%+.2f", c.stats.Diff), c.stats.Diff))
+		}
+	}
+
+	return append(lines, row("Investments Sum", out.InvestmentsSum))
+}
+
+func (s *tuiState) watchlistLines() []string {
+	lines := []string{header("Watchlist")}
+	for i, q := range s.data.quotes {
+		line := fmt.Sprintf("%-10s %12s ", fit(q.Symbol, 10), formatPrice(q.Price))
+		line = line + colored(fmt.Sprintf("%7.2f%%", q.Pct), q.Pct) + "  " + q.State
+		lines = append(lines, s.selectable(paneWatchlist, i, line))
+	}
+
+	return lines
+}
+
+func (s *tuiState) holdingLines(max int) []string {
+	lines := []string{header("Portfolio"), fmt.Sprintf("  %-10s %12s %12s %12s %12s %8s", "SYMBOL", "UNITS", "PRICE", "SUM", "DIFF", "PCT")}
+
+	// scroll to keep the selection visible
+	rows := max - len(lines)
+	first := 0
+	if s.selected[paneHoldings] >= rows {
+		first = s.selected[paneHoldings] - rows + 1
+	}
+
+	for i, h := range s.data.holdings {
+		if i < first || i >= first+rows {
+			continue
+		}
+
+		line := fmt.Sprintf("%-10s %12s %12s %12.2f ", fit(h.Symbol, 10), formatUnits(h.Units), formatPrice(h.Quote.Price), h.Sum)
+		line = line + colored(fmt.Sprintf("%12.2f", h.Diff), h.Diff) + " " + colored(fmt.Sprintf("%8.2f", h.Quote.Pct), h.Quote.Pct)
+		lines = append(lines, s.selectable(paneHoldings, i, line))
+	}
+
+	return lines
+}
+
+func (s *tuiState) chartLines(height int) []string {
+	periods := []string{}
+	for i, p := range tuiPeriods {
+		if i == s.period {
+			p = "\033[7m" + p + "\033[0m"
+		}
+
+		periods = append(periods, fmt.Sprintf("%d %s", i+1, p))
+	}
+
+	lines := []string{header("Chart "+s.chart.symbol) + "  " + strings.Join(periods, "  ")}
+	if s.chart.err != nil {
+		return append(lines, s.chart.err.Error())
+	}
+
+	if len(s.chart.bars) == 0 || height < 4 {
+		return append(lines, "loading...")
+	}
+
+	data := []float64{}
+	for _, b := range s.chart.bars {
+		data = append(data, b.Close)
+	}
+
+	plot := asciigraph.Plot(data, asciigraph.Height(height-3), asciigraph.Width(s.width-14))
+	for _, line := range strings.Split(plot, "\n") {
+		lines = append(lines, fit(line, s.width))
+	}
+
+	return lines
+}
+
+// selectable marks the selected line of the focused pane.
+func (s *tuiState) selectable(pane int, i int, line string) string {
+	if s.selected[pane] != i {
+		return "  " + line
+	}
+
+	if s.focus == pane {
+		return "\033[1m> " + line
+	}
+
+	return "> " + line
+}
+
+func header(title string) string {
+	return "\033[1;4m" + title + "\033[0m"
+}
+
+func row(label string, value float64) string {
+	return fmt.Sprintf("%-16s %12.2f", label, value)
+}
+
+func colored(s string, value float64) string {
+	if value < 0 {
+		return "\033[31m" + s + "\033[0m"
+	}
+
+	return "\033[32m" + s + "\033[0m"
+}
+
+// fit truncates or pads a string without escape sequences to width.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:width])
+	}
+
+	return s + strings.Repeat(" ", width-n)
+}
+
+// joinColumns puts the lines of right next to the lines of left at column
+// width.
+func joinColumns(left []string, right []string, width int) []string {
+	lines := []string{}
+	for i := 0; i < len(left) || i < len(right); i++ {
+		l := ""
+		if i < len(left) {
+			l = left[i]
+		}
+
+		line := l + strings.Repeat(" ", maxInt(width-visibleLen(l), 1))
+		if i < len(right) {
+			line = line + right[i]
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// visibleLen returns the number of runes of s without escape sequences.
+func visibleLen(s string) int {
+	n := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case r == '\033':
+			escape = true
+		case escape:
+			if r >= 'A' && r <= 'z' && r != '[' {
+				escape = false
+			}
+		default:
+			n++
+		}
+	}
+
+	return n
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
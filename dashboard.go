@@ -0,0 +1,100 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FileServer(http.FS(files))
+}
+
+// Events sends the stats of every refresh to the dashboards as Server-Sent
+// Events. A new client gets the last stats right away.
+type Events struct {
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+	last    []byte
+}
+
+func newEvents() *Events {
+	return &Events{clients: make(map[chan []byte]bool)}
+}
+
+func (e *Events) publish(out Out) {
+	data, err := json.Marshal(out)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.last = data
+	for client := range e.clients {
+		// Slow clients miss an update instead of blocking the others.
+		select {
+		case client <- data:
+		default:
+		}
+	}
+}
+
+func (e *Events) subscribe() chan []byte {
+	client := make(chan []byte, 1)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.clients[client] = true
+	if e.last != nil {
+		client <- e.last
+	}
+
+	return client
+}
+
+func (e *Events) unsubscribe(client chan []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.clients, client)
+}
+
+func (e *Events) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	client := e.subscribe()
+	defer e.unsubscribe(client)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-client:
+			fmt.Fprintf(w, "event: sum\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
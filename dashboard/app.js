@@ -0,0 +1,246 @@
+'use strict';
+
+const state = {
+  history: {period: '30d', interval: 'daily', field: 'Total'},
+  symbol: {name: '', period: '1mo'},
+};
+
+function formatNumber(n) {
+  return n.toLocaleString(undefined, {minimumFractionDigits: 2, maximumFractionDigits: 2});
+}
+
+function formatUnits(n) {
+  return String(Math.round(n * 1e8) / 1e8);
+}
+
+function formatPrice(p) {
+  return p < 0.1 && p !== 0 ? p.toFixed(8) : formatNumber(p);
+}
+
+function colorClass(n) {
+  return n < 0 ? 'down' : 'up';
+}
+
+function el(tag, attrs, text) {
+  const e = document.createElement(tag);
+  Object.keys(attrs || {}).forEach((k) => e.setAttribute(k, attrs[k]));
+  if (text !== undefined) {
+    e.textContent = text;
+  }
+
+  return e;
+}
+
+function svgEl(tag, attrs, text) {
+  const e = document.createElementNS('http://www.w3.org/2000/svg', tag);
+  Object.keys(attrs || {}).forEach((k) => e.setAttribute(k, attrs[k]));
+  if (text !== undefined) {
+    e.textContent = text;
+  }
+
+  return e;
+}
+
+async function getJSON(url) {
+  const res = await fetch(url);
+  const data = await res.json();
+  if (!res.ok) {
+    throw new Error(data.error || res.statusText);
+  }
+
+  return data;
+}
+
+// Investments are in USD, labeled if the output currency is another.
+function renderCards(out) {
+  const currency = out.Currency || 'USD';
+  const own = currency === 'USD' ? '' : ' (' + currency + ')';
+  const usd = currency === 'USD' ? '' : ' (USD)';
+  const cards = [
+    {label: 'Total' + own, value: out.Total},
+    {label: 'Savings' + own, value: out.Savings},
+    {label: 'Investments Sum' + usd, value: out.InvestmentsSum},
+  ];
+
+  [['Stocks', out.Stocks], ['Assets', out.Assets], ['Crypto', out.Crypto]].forEach(([name, stats]) => {
+    if (stats.Sum > 0 || stats.Realized !== 0) {
+      cards.push({label: name + ' Sum' + usd, value: stats.Sum, diff: stats.Diff});
+    }
+  });
+
+  const container = document.getElementById('cards');
+  container.replaceChildren(...cards.map((c) => {
+    const card = el('div', {class: 'card'});
+    card.append(el('div', {class: 'label'}, c.label), el('div', {class: 'value'}, formatNumber(c.value)));
+    if (c.diff !== undefined) {
+      card.append(el('div', {class: 'diff ' + colorClass(c.diff)}, (c.diff >= 0 ? '+' : '') + formatNumber(c.diff)));
+    }
+
+    return card;
+  }));
+}
+
+async function loadPortfolio() {
+  const portfolio = await getJSON('/api/portfolio');
+  document.getElementById('portfolio-title').textContent = 'Portfolio in ' + portfolio.Currency;
+  const body = document.querySelector('#portfolio tbody');
+  body.replaceChildren(...portfolio.Details.map((d) => {
+    const date = d.Date.startsWith('0001-') ? '' : d.Date.slice(0, 10);
+    const row = el('tr');
+    row.append(
+      el('td', {}, d.Quote.Symbol),
+      el('td', {}, date),
+      el('td', {}, formatUnits(d.Units)),
+      el('td', {}, formatNumber(d.Sum)),
+      el('td', {}, formatNumber(d.In)),
+      el('td', {class: colorClass(d.Diff)}, formatNumber(d.Diff)),
+      el('td', {}, formatNumber(d.Realized)),
+      el('td', {}, formatPrice(d.Quote.Price)),
+      el('td', {class: colorClass(d.Quote.Pct)}, d.Quote.Pct.toFixed(2)),
+    );
+    row.addEventListener('click', () => showSymbol(d.Quote.Symbol));
+    return row;
+  }));
+}
+
+async function loadHistory() {
+  const h = state.history;
+  const rows = await getJSON('/api/history?last=' + h.period + '&interval=' + h.interval);
+  lineChart(document.getElementById('history-chart'), rows.map((r) => ({
+    time: new Date(r.Date),
+    value: r[h.field],
+  })));
+}
+
+async function loadSymbolChart() {
+  const s = state.symbol;
+  if (!s.name) {
+    return;
+  }
+
+  const bars = await getJSON('/api/chart/' + encodeURIComponent(s.name) + '?period=' + s.period);
+  lineChart(document.getElementById('symbol-chart'), bars.map((b) => ({
+    time: new Date(b.Time),
+    value: b.Close,
+  })));
+}
+
+function showSymbol(symbol) {
+  state.symbol.name = symbol;
+  document.getElementById('symbol').hidden = false;
+  document.getElementById('symbol-name').textContent = symbol;
+  loadSymbolChart().catch(showError);
+  document.getElementById('symbol').scrollIntoView({behavior: 'smooth'});
+}
+
+// lineChart draws the points into the svg and shows the nearest point on
+// hover.
+function lineChart(svg, points) {
+  const width = svg.clientWidth || 800;
+  const height = svg.clientHeight || 260;
+  const pad = {top: 16, right: 16, bottom: 24, left: 80};
+  svg.setAttribute('viewBox', '0 0 ' + width + ' ' + height);
+  svg.replaceChildren();
+
+  if (points.length === 0) {
+    svg.append(svgEl('text', {x: width / 2, y: height / 2, 'text-anchor': 'middle'}, 'No data'));
+    return;
+  }
+
+  const times = points.map((p) => p.time.getTime());
+  const values = points.map((p) => p.value);
+  const minT = Math.min(...times);
+  const maxT = Math.max(...times);
+  let minV = Math.min(...values);
+  let maxV = Math.max(...values);
+  if (minV === maxV) {
+    minV = minV - 1;
+    maxV = maxV + 1;
+  }
+
+  const x = (t) => pad.left + (maxT === minT ? 0.5 : (t - minT) / (maxT - minT)) * (width - pad.left - pad.right);
+  const y = (v) => pad.top + (1 - (v - minV) / (maxV - minV)) * (height - pad.top - pad.bottom);
+
+  [minV, (minV + maxV) / 2, maxV].forEach((v) => {
+    svg.append(svgEl('line', {x1: pad.left, x2: width - pad.right, y1: y(v), y2: y(v)}));
+    svg.append(svgEl('text', {x: pad.left - 8, y: y(v) + 4, 'text-anchor': 'end'}, formatNumber(v)));
+  });
+
+  svg.append(svgEl('text', {x: pad.left, y: height - 6}, new Date(minT).toLocaleDateString()));
+  svg.append(svgEl('text', {x: width - pad.right, y: height - 6, 'text-anchor': 'end'}, new Date(maxT).toLocaleDateString()));
+
+  const d = points.map((p, i) => (i === 0 ? 'M' : 'L') + x(p.time.getTime()) + ' ' + y(p.value)).join(' ');
+  svg.append(svgEl('path', {d: d}));
+
+  const dot = svgEl('circle', {r: 4, visibility: 'hidden'});
+  const label = svgEl('text', {visibility: 'hidden'});
+  svg.append(dot, label);
+
+  svg.onmousemove = (e) => {
+    const rect = svg.getBoundingClientRect();
+    const mx = (e.clientX - rect.left) * width / rect.width;
+    let nearest = points[0];
+    points.forEach((p) => {
+      if (Math.abs(x(p.time.getTime()) - mx) < Math.abs(x(nearest.time.getTime()) - mx)) {
+        nearest = p;
+      }
+    });
+
+    const px = x(nearest.time.getTime());
+    const py = y(nearest.value);
+    dot.setAttribute('cx', px);
+    dot.setAttribute('cy', py);
+    label.setAttribute('x', Math.min(px + 8, width - 200));
+    label.setAttribute('y', Math.max(py - 8, 12));
+    label.textContent = nearest.time.toLocaleString() + '  ' + formatNumber(nearest.value);
+    dot.setAttribute('visibility', 'visible');
+    label.setAttribute('visibility', 'visible');
+  };
+
+  svg.onmouseleave = () => {
+    dot.setAttribute('visibility', 'hidden');
+    label.setAttribute('visibility', 'hidden');
+  };
+}
+
+function selectPeriod(container, onSelect) {
+  container.querySelectorAll('button').forEach((button) => {
+    button.addEventListener('click', () => {
+      container.querySelectorAll('button').forEach((b) => b.classList.remove('active'));
+      button.classList.add('active');
+      onSelect(button.dataset);
+    });
+  });
+}
+
+function showError(err) {
+  document.getElementById('status').textContent = 'Error: ' + err.message;
+}
+
+function refresh(out) {
+  renderCards(out);
+  document.getElementById('status').textContent = 'Updated ' + new Date(out.Date).toLocaleTimeString();
+  Promise.all([loadPortfolio(), loadHistory(), loadSymbolChart()]).catch(showError);
+}
+
+selectPeriod(document.getElementById('history-periods'), (data) => {
+  state.history.period = data.period;
+  state.history.interval = data.interval;
+  loadHistory().catch(showError);
+});
+
+selectPeriod(document.getElementById('symbol-periods'), (data) => {
+  state.symbol.period = data.period;
+  loadSymbolChart().catch(showError);
+});
+
+document.getElementById('history-field').addEventListener('change', (e) => {
+  state.history.field = e.target.value;
+  loadHistory().catch(showError);
+});
+
+const events = new EventSource('/api/events');
+events.addEventListener('sum', (e) => refresh(JSON.parse(e.data)));
+events.onerror = () => {
+  document.getElementById('status').textContent = 'Reconnecting...';
+};
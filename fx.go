@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// minorUnit is a currency quoted in a fraction of another currency.
+type minorUnit struct {
+	currency string
+	units    float64
+}
+
+// minorUnits are the currencies of quotes in cents, like London stocks in
+// pence.
+var minorUnits = map[string]minorUnit{
+	"GBp": {"GBP", 100},
+	"GBX": {"GBP", 100},
+	"ZAc": {"ZAR", 100},
+	"ILA": {"ILS", 100},
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func validateCurrency(currency string) error {
+	if _, ok := minorUnits[currency]; ok || currency == "" || currencyPattern.MatchString(currency) {
+		return nil
+	}
+
+	return fmt.Errorf("Invalid currency %q, expected an ISO code like EUR", currency)
+}
+
+// validateCurrencies checks the config currency and the currencies of all
+// orders and payments.
+func validateCurrencies(c *Conf) error {
+	for _, currency := range c.currencies() {
+		if err := validateCurrency(currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currencies returns the config currency and the currencies of all orders,
+// amounts and payments of a config, with duplicates and empty ones.
+func (c *Conf) currencies() []string {
+	currencies := []string{c.Currency}
+	for _, investments := range c.investmentOrders() {
+		for _, orders := range investments {
+			for _, order := range orders {
+				currencies = append(currencies, order.Currency)
+			}
+		}
+	}
+
+	for _, amounts := range []map[string]Amount{c.Savings, c.Income, c.Expenses} {
+		for _, a := range amounts {
+			currencies = append(currencies, a.Currency)
+		}
+	}
+
+	for _, payments := range []map[string][]Payment{c.Investments.Dividends, c.Interest} {
+		for _, p := range payments {
+			for _, payment := range p {
+				currencies = append(currencies, payment.Currency)
+			}
+		}
+	}
+
+	return currencies
+}
+
+// getCurrency returns the units of a currency per USD, or 0 if the rate
+// could not be fetched. Rates of other currencies are crossed through USD.
+func getCurrency(name string) float64 {
+	rate, _ := getRate(name)
+	return rate
+}
+
+// getRate is getCurrency with the time since when the rate is stale, if the
+// cache served it because the provider failed.
+func getRate(name string) (float64, *time.Time) {
+	if name == "" || name == "USD" {
+		return 1.0, nil
+	}
+
+	if minor, ok := minorUnits[name]; ok {
+		rate, stale := getRate(minor.currency)
+		return rate * minor.units, stale
+	}
+
+	rate, err := provider.Rate(name)
+	if e, ok := err.(StaleError); ok {
+		return rate, &e.Since
+	}
+
+	return rate, nil
+}
+
+// getCurrencyAt returns the rate of a currency at a past date, the close of
+// the last trading day up to the date. Rates are read from the fx store, or
+// fetched from the chart of the provider and added to the store.
+func getCurrencyAt(name string, date time.Time) (float64, error) {
+	if name == "" || name == "USD" {
+		return 1.0, nil
+	}
+
+	if minor, ok := minorUnits[name]; ok {
+		rate, err := getCurrencyAt(minor.currency, date)
+		return rate * minor.units, err
+	}
+
+	if date.IsZero() {
+		return getCurrency(name), nil
+	}
+
+	if rate, ok := fxStore.rateAt(name, date); ok {
+		return rate, nil
+	}
+
+	end := date.AddDate(0, 0, 1)
+	bars, err := provider.RateChart(name, ChartParams{
+		Start:    date.AddDate(0, 0, -fxLookback),
+		End:      end,
+		Interval: "1d",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rate := 0.0
+	rates := make(map[string]float64)
+	for _, b := range bars {
+		if b.Time.Before(end) {
+			rate = b.Close
+			rates[b.Time.Format("2006-01-02")] = b.Close
+		}
+	}
+
+	fxStore.add(map[string]map[string]float64{name: rates})
+
+	if rate == 0 {
+		return 0, fmt.Errorf("Could not find %s rate at %s", name, date.Format("2006-01-02"))
+	}
+
+	return rate, nil
+}
+
+// currency returns the currency of an amount of a symbol: the given one, or
+// the currency of the quote, or USD.
+func (s QuoteSet) currency(symbol string, currency string) string {
+	if currency != "" {
+		return currency
+	}
+
+	if q, ok := s.Quotes[symbol]; ok && q.Currency != "" {
+		return q.Currency
+	}
+
+	return "USD"
+}
+
+// symbolFactor returns the rate to convert the prices of the orders of a
+// symbol to USD.
+func (s QuoteSet) symbolFactor(symbol string) func(Order) float64 {
+	return func(order Order) float64 {
+		return s.currencyFactor(s.currency(symbol, order.Currency))
+	}
+}
+
+// paymentFactor returns the rate to convert the payments of a symbol to USD.
+func (s QuoteSet) paymentFactor(symbol string) func(string) float64 {
+	return func(currency string) float64 {
+		return s.currencyFactor(s.currency(symbol, currency))
+	}
+}
+
+// convert converts an amount between two currencies through USD.
+func (s QuoteSet) convert(amount float64, from string, to string) float64 {
+	return amount / s.currencyFactor(from) * s.currencyFactor(to)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// fxLookback is the number of days before a date searched for a rate, for
+// weekends and holidays without rates.
+const fxLookback = 7
+
+// FXStore keeps the historical rates of currencies, as units per USD by
+// date, in finances/fx/rates.csv. Without the finances dir the rates are
+// only kept in memory.
+type FXStore struct {
+	mu     sync.Mutex
+	path   string
+	rates  map[string]map[string]float64
+	loaded bool
+}
+
+var fxStore = &FXStore{}
+
+// useFXStore selects the rate store in the data dir of the config file.
+func useFXStore(configFile string) {
+	path := ""
+	if dir, err := getOutDir(configFile); err == nil {
+		path = filepath.Join(dir, "fx", "rates.csv")
+	}
+
+	if fxStore.path != path || path == "" {
+		fxStore = &FXStore{path: path}
+	}
+}
+
+func (s *FXStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	// A file which can not be read is read again on the next call, so new
+	// rates are never appended to a broken file.
+	s.rates = make(map[string]map[string]float64)
+	if s.path == "" {
+		s.loaded = true
+		return nil
+	}
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		s.loaded = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return fmt.Errorf("in file %q: %v", s.path, err)
+	}
+
+	for i, record := range records {
+		if i == 0 && record[0] == "date" {
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return fmt.Errorf("in file %q line %d: %v", s.path, i+1, err)
+		}
+
+		s.set(record[1], record[0], rate)
+	}
+
+	s.loaded = true
+	return nil
+}
+
+func (s *FXStore) set(currency string, date string, rate float64) {
+	if s.rates[currency] == nil {
+		s.rates[currency] = make(map[string]float64)
+	}
+
+	s.rates[currency][date] = rate
+}
+
+// rateAt returns the rate of a currency at a date, or of the last day with a
+// rate before.
+func (s *FXStore) rateAt(currency string, date time.Time) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return 0, false
+	}
+
+	for i := 0; i <= fxLookback; i++ {
+		if rate, ok := s.rates[currency][date.AddDate(0, 0, -i).Format("2006-01-02")]; ok {
+			return rate, true
+		}
+	}
+
+	return 0, false
+}
+
+// add stores the rates of currencies by date. New rates are appended to the
+// file, so rates imported before keep their place.
+func (s *FXStore) add(rates map[string]map[string]float64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+
+	rows := [][]string{}
+	for currency, dates := range rates {
+		for date, rate := range dates {
+			if old, ok := s.rates[currency][date]; ok && old == rate {
+				continue
+			}
+
+			s.set(currency, date, rate)
+			rows = append(rows, []string{date, currency, strconv.FormatFloat(rate, 'f', -1, 64)})
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0]+rows[i][1] < rows[j][0]+rows[j][1]
+	})
+
+	if s.path == "" || len(rows) == 0 {
+		return len(rows), nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(s.path), 0755)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if info, err := os.Stat(s.path); err != nil || info.Size() == 0 {
+		w.Write([]string{"date", "currency", "rate"})
+	}
+
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+
+	return len(rows), appendFile(s.path, buf.Bytes())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetCurrencyAt(t *testing.T) {
+	useFixtures(t, map[string]Quote{}, nil)
+	useRates(t, nil)
+	writeFixture(t, filepath.Join(fixturesDir, "rates", "EUR.yaml"), []Bar{
+		{Time: date("2022-05-31"), Close: 0.93},
+		{Time: date("2022-06-01"), Close: 0.94},
+		{Time: date("2022-06-03"), Close: 0.95},
+	})
+
+	rate, err := getCurrencyAt("EUR", date("2022-06-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFloat(t, "EUR rate", rate, 0.94)
+
+	stored, ok := fxStore.rateAt("EUR", date("2022-05-31"))
+	if !ok {
+		t.Fatal("rate of 2022-05-31 not stored")
+	}
+
+	assertFloat(t, "stored EUR rate", stored, 0.93)
+
+	rate, err = getCurrencyAt("GBp", date("2022-06-02"))
+	if err == nil {
+		t.Errorf("got GBp rate %v without GBP fixture", rate)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Payment is a dividend of a symbol or the interest of a savings account.
+type Payment struct {
+	Date     time.Time
+	Amount   float64
+	Currency string
+}
+
+// Amount is a savings, income or expense entry. It is written as a number in
+// the config currency or as {amount: 1200, currency: USD}.
+type Amount struct {
+	Amount   float64
+	Currency string
+}
+
+// UnmarshalYAML ...
+func (a *Amount) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var n float64
+	if err := unmarshal(&n); err == nil {
+		*a = Amount{Amount: n}
+		return nil
+	}
+
+	type plain Amount
+	return unmarshal((*plain)(a))
+}
+
+// amountsSum converts the amounts to the config currency. Amounts can not be
+// skipped, so a stale rate is an error unless the policy is stale.
+func amountsSum(c *Conf, amounts map[string]Amount, quotes QuoteSet, policy string) (float64, error) {
+	sum := 0.0
+	for _, a := range amounts {
+		currency := c.amountCurrency(a)
+		if err := quotes.checkRate(currency, policy); err != nil {
+			return 0, err
+		}
+
+		sum = sum + quotes.convert(a.Amount, currency, c.Currency)
+	}
+
+	return sum, nil
+}
+
+// amountCurrency returns the currency of an amount, default is the config
+// currency.
+func (c *Conf) amountCurrency(a Amount) string {
+	if a.Currency == "" {
+		return c.Currency
+	}
+
+	return a.Currency
+}
+
+// foreignAmountRows returns a row per amount in another currency than the
+// config currency, with the converted and the original amount.
+func foreignAmountRows(c *Conf, label string, amounts map[string]Amount, quotes QuoteSet) [][]string {
+	currency := c.outputCurrency()
+	names := []string{}
+	for name, a := range amounts {
+		if a.Currency != "" && a.Currency != currency {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	rows := [][]string{}
+	for _, name := range names {
+		a := amounts[name]
+		rows = append(rows, []string{
+			label + " " + name,
+			fmt.Sprintf("%.2f (%.2f %s)", quotes.convert(a.Amount, a.Currency, c.Currency), a.Amount, a.Currency),
+		})
+	}
+
+	return rows
+}
+
+// paymentsSum converts each payment by the factor of its currency.
+func paymentsSum(payments []Payment, factor func(string) float64) float64 {
+	sum := 0.0
+	for _, payment := range payments {
+		sum = sum + payment.Amount/factor(payment.Currency)
+	}
+
+	return sum
+}
+
+// totalReturn is the return of the price and the income of investments.
+func (s InvestmentStats) totalReturn() float64 {
+	return s.Diff + s.Realized + s.Income
+}
+
+// interestSum returns the interest of all savings accounts in the config
+// currency. Payments without currency are in the config currency. Like
+// amounts, interest can not be skipped if a rate is unusable.
+func interestSum(c *Conf, quotes QuoteSet, policy string) (float64, error) {
+	sum := 0.0
+	for _, payments := range c.Interest {
+		for _, payment := range payments {
+			currency := payment.Currency
+			if currency == "" {
+				currency = c.Currency
+			}
+
+			if err := quotes.checkRate(currency, policy); err != nil {
+				return 0, err
+			}
+
+			sum = sum + quotes.convert(payment.Amount, currency, c.Currency)
+		}
+	}
+
+	return sum, nil
+}
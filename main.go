@@ -2,54 +2,121 @@ package main
 
 import (
 	"fmt"
-	"github.com/piquette/finance-go/quote"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"time"
 )
 
 // Order ...
 type Order struct {
+	Date     time.Time
+	Side     string
 	Units    float64
 	In       float64
+	Price    float64
+	Fee      float64
 	Currency string
 }
 
 // Conf ...
 type Conf struct {
 	Currency    string
-	Savings     map[string]float64
+	Savings     map[string]Amount
 	Investments struct {
-		Assets map[string][]Order
-		Stocks map[string][]Order
-		Crypto map[string][]Order
+		Assets    map[string][]Order
+		Stocks    map[string][]Order
+		Crypto    map[string][]Order
+		Dividends map[string][]Payment
 	}
-	Income   map[string]float64
-	Expenses map[string]float64
+	Interest map[string][]Payment
+	Income   map[string]Amount
+	Expenses map[string]Amount
+	Provider string
+	Cache    CacheConf
+	Snapshot SnapshotConf
+
+	// Markets adds holidays and early closes to the calendars of markets.
+	Markets map[string][]MarketDay
+
+	// CostBasis is the method to match sells to lots, and CostBasisSymbols
+	// overrides it per symbol.
+	CostBasis        string            `yaml:"cost_basis"`
+	CostBasisSymbols map[string]string `yaml:"cost_basis_symbols"`
+}
+
+// outputCurrency returns the currency of the output, default is USD.
+func (c *Conf) outputCurrency() string {
+	if c.Currency == "" {
+		return "USD"
+	}
+
+	return c.Currency
+}
+
+// investmentOrders returns the orders by symbol of the stocks, assets and
+// crypto of a config, in this order.
+func (c *Conf) investmentOrders() []map[string][]Order {
+	return []map[string][]Order{
+		c.Investments.Stocks,
+		c.Investments.Assets,
+		c.Investments.Crypto,
+	}
+}
+
+// confSymbols returns the symbols of all investments of a config.
+func confSymbols(c *Conf) []string {
+	symbols := []string{}
+	for _, investments := range c.investmentOrders() {
+		for symbol := range investments {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	sort.Strings(symbols)
+	return symbols
 }
 
 // InvestmentStats ...
 type InvestmentStats struct {
-	Sum     float64
-	In      float64
-	Diff    float64
-	Loss    float64
-	Details map[string][]InvestmentDetail `yaml:"-"`
+	Sum      float64
+	In       float64
+	Diff     float64
+	Loss     float64
+	Realized float64
+	Fees     float64
+	Income   float64
+	Details  map[string][]InvestmentDetail `yaml:"-" json:",omitempty"`
+	Symbols  map[string]SymbolSnapshot     `yaml:"symbols,omitempty"`
+}
+
+// SymbolSnapshot is the position of a symbol stored in snapshots. Price is
+// the quote price and Rate the units of Currency per USD to convert it.
+type SymbolSnapshot struct {
+	Units    float64
+	Price    float64
+	Currency string `yaml:",omitempty"`
+	Rate     float64
+	Value    float64
 }
 
 // InvestmentDetail ...
 type InvestmentDetail struct {
-	Units float64
-	Sum   float64
-	In    float64
-	Diff  float64
-	Quote Quote
+	Date     time.Time
+	Units    float64
+	Sum      float64
+	In       float64
+	Diff     float64
+	Realized float64
+	Quote    Quote
 }
 
 // Quote ...
@@ -59,7 +126,11 @@ type Quote struct {
 	Symbol     string
 	State      string
 	Name       string
-	MarketInfo MarketInfo
+	Market     string
+	Timezone   string
+	Currency   string     `yaml:",omitempty"`
+	StaleSince *time.Time `yaml:"stale_since,omitempty"`
+	MarketInfo MarketInfo `yaml:"-"`
 }
 
 var client = &http.Client{Timeout: 10 * time.Second}
@@ -68,12 +139,62 @@ func main() {
 	app := &cli.App{
 		Name:  "fin-stats",
 		Usage: "",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "provider",
+				Value:   "",
+				Usage:   "quote provider, default is yahoo",
+				EnvVars: []string{"FIN_STATS_PROVIDER"},
+			},
+			&cli.BoolFlag{
+				Name:  "offline",
+				Value: false,
+				Usage: "read quotes from the fixtures dir instead of the network",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   outputTable,
+				Usage:   "table, json, csv, markdown or yaml",
+			},
+			&cli.StringFlag{
+				Name:  "fixtures",
+				Value: "",
+				Usage: "fixtures dir, default is fixtures next to the config",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := useOutput(c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			fixturesDir = c.String("fixtures")
+			if c.Bool("offline") {
+				providerFlag = true
+				return useProvider("fixture")
+			}
+
+			providerFlag = c.IsSet("provider")
+			return useProvider(c.String("provider"))
+		},
+		After: func(c *cli.Context) error {
+			flushCache()
+			return nil
+		},
 		Commands: []*cli.Command{
 			cmdSum(),
 			cmdQuote(),
 			cmdGraph(),
 			cmdPortfolio(),
 			cmdTrending(),
+			cmdLots(),
+			cmdTax(),
+			cmdHistory(),
+			cmdDiff(),
+			cmdServe(),
+			cmdTUI(),
+			cmdFX(),
 		},
 	}
 
@@ -84,32 +205,16 @@ func main() {
 }
 
 func getQuote(symbol string, fail bool) (Quote, error) {
-	result := Quote{}
-	q, err := quote.Get(symbol)
+	result, err := provider.Quote(symbol)
 	if err != nil {
 		return result, err
 	}
 
-	if q == nil {
-		return result, fmt.Errorf("Could not find quote by symbol %s", symbol)
-	}
-
-	result.Price = q.RegularMarketPrice
-	result.Pct = q.RegularMarketChangePercent
-
-	if q.MarketState == "PRE" && q.PreMarketPrice > 0 {
-		result.Price = q.PreMarketPrice
-		result.Pct = q.PreMarketChangePercent
-	} else if q.MarketState == "POST" && q.PostMarketPrice > 0 {
-		result.Price = q.PostMarketPrice
-		result.Pct = q.PostMarketChangePercent
+	result.MarketInfo = getMarketInfo(result.Market, result.Timezone)
+	if session := result.MarketInfo.Session; session != nil {
+		result.State = string(session.State)
 	}
 
-	result.Symbol = q.Symbol
-	result.State = string(q.MarketState)
-	result.Name = q.ShortName
-	result.MarketInfo = getMarketInfo(*q)
-
 	return result, nil
 }
 
@@ -121,53 +226,119 @@ func formatPrice(p float64) string {
 	return fmt.Sprintf("%.2f", p)
 }
 
-func getCurrency(name string) float64 {
-	if name == "EUR" {
-		q, _ := getQuote("EUR=X", true)
-		return q.Price
-	}
-
-	return 1.0
+func formatUnits(u float64) string {
+	return strconv.FormatFloat(math.Round(u*1e8)/1e8, 'f', -1, 64)
 }
 
-func getInvestmentsStats(investments map[string][]Order) InvestmentStats {
+// getInvestmentsStats returns QuoteErrors if a quote or rate of a symbol is
+// missing. Those symbols are left out of the stats with the skip policy,
+// otherwise the stats are incomplete and must not be used.
+func getInvestmentsStats(investments map[string][]Order, quotes QuoteSet, policy string, costBasis func(string) string) (InvestmentStats, error) {
 	sum := 0.0
 	sumIn := 0.0
 	loss := 0.0
+	realizedSum := 0.0
+	fees := 0.0
 	details := make(map[string][]InvestmentDetail)
+	symbols := make(map[string]SymbolSnapshot)
+	errs := QuoteErrors{}
 
 	for symbol, orders := range investments {
-		for _, order := range orders {
-			quote, _ := getQuote(symbol, true)
-			price := quote.Price
-			in := order.In
-
-			if order.Currency != "" && order.Currency != "USD" {
-				factor := getCurrency(order.Currency)
-				price = price / factor
-				in = in / factor
+		err := quotes.check(symbol, policy)
+		if err == nil {
+			err = quotes.checkRates(symbol, orders, policy)
+		}
+
+		if err != nil {
+			errs[symbol] = err
+			continue
+		}
+
+		quote := quotes.Quotes[symbol]
+		currency := quotes.currency(symbol, "")
+		factor := quotes.currencyFactor(currency)
+		lots, sales, fee := matchLots(orders, quotes.symbolFactor(symbol), costBasis(symbol))
+		realizedSum = realizedSum + realizedGain(sales)
+		fees = fees + fee
+
+		for _, l := range lots {
+			detail := l.InvestmentDetail
+			detail.Sum = quote.Price / factor * detail.Units
+			detail.Diff = detail.Sum - detail.In
+			detail.Quote = quote
+			sum = sum + detail.Sum
+			sumIn = sumIn + detail.In
+
+			details[symbol] = append(details[symbol], detail)
+			if detail.In > detail.Sum {
+				loss = loss + detail.In - detail.Sum
 			}
 
-			total := price * order.Units
-			totalIn := in * order.Units
-			sum = sum + total
-			sumIn = sumIn + totalIn
-			detail := InvestmentDetail{
-				Units: order.Units,
-				In:    totalIn,
-				Sum:   total,
-				Diff:  total - totalIn,
-				Quote: quote,
+			s := symbols[symbol]
+			s.Units = s.Units + detail.Units
+			s.Price = quote.Price
+			s.Currency = currency
+			s.Rate = factor
+			s.Value = s.Value + detail.Sum
+			symbols[symbol] = s
+		}
+	}
+
+	stats := InvestmentStats{
+		Sum:      sum,
+		In:       sumIn,
+		Diff:     sum - sumIn,
+		Loss:     loss,
+		Realized: realizedSum,
+		Fees:     fees,
+		Details:  details,
+		Symbols:  symbols,
+	}
+	if len(errs) > 0 {
+		return stats, errs
+	}
+
+	return stats, nil
+}
+
+// getConfStats returns the stats of the stocks, assets and crypto of a
+// config and the merged QuoteErrors of all three. Symbols with an unusable
+// rate of a dividend are left out like symbols without quote.
+func getConfStats(c *Conf, quotes QuoteSet, policy string) (InvestmentStats, InvestmentStats, InvestmentStats, error) {
+	errs := QuoteErrors{}
+	stats := []InvestmentStats{}
+
+	for _, investments := range c.investmentOrders() {
+		valid := make(map[string][]Order)
+		for symbol, orders := range investments {
+			if err := quotes.checkPayments(symbol, c.Investments.Dividends[symbol], policy); err != nil {
+				errs[symbol] = err
+				continue
 			}
 
-			details[symbol] = append(details[symbol], detail)
-			if totalIn > total {
-				loss = loss + totalIn - total
+			valid[symbol] = orders
+		}
+
+		s, err := getInvestmentsStats(valid, quotes, policy, c.costBasis)
+		e, _ := err.(QuoteErrors)
+		for symbol := range valid {
+			if _, ok := e[symbol]; !ok {
+				s.Income = s.Income + paymentsSum(c.Investments.Dividends[symbol], quotes.paymentFactor(symbol))
 			}
 		}
+
+		for symbol, err := range e {
+			errs[symbol] = err
+		}
+
+		stats = append(stats, s)
+	}
+
+	if len(errs) > 0 {
+		return stats[0], stats[1], stats[2], errs
 	}
 
-	return InvestmentStats{sum, sumIn, sum - sumIn, loss, details}
+	return stats[0], stats[1], stats[2], nil
 }
 
 func getHomeDir() string {
@@ -218,6 +389,53 @@ func findConfigFile(file string) (string, error) {
 	return filename, nil
 }
 
+func loadConf(file string) (*Conf, string, error) {
+	conf := &Conf{}
+	filename, err := findConfigFile(file)
+	if err != nil {
+		return conf, filename, err
+	}
+
+	if fixturesDir == "" {
+		fixturesDir = filepath.Join(filepath.Dir(filename), "fixtures")
+	}
+
+	err = readYaml(filename, conf)
+	if err != nil {
+		return conf, filename, err
+	}
+
+	err = validateOrders(conf)
+	if err == nil {
+		err = validateRetention(conf.Snapshot.Retention)
+	}
+
+	if err == nil {
+		err = validateCurrencies(conf)
+	}
+
+	if err == nil {
+		err = validateStore(conf.Snapshot)
+	}
+
+	if err == nil {
+		err = validateMarkets(conf.Markets)
+	}
+
+	if err != nil {
+		return conf, filename, fmt.Errorf("in file %q: %v", filename, err)
+	}
+
+	if !providerFlag {
+		err = useProvider(conf.Provider)
+	}
+
+	useCache(conf.Cache, filename)
+	useFXStore(filename)
+	useMarkets(conf.Markets)
+	return conf, filename, err
+}
+
 func readYaml(filename string, in interface{}) error {
 	buf, err := ioutil.ReadFile(filename)
 	if err != nil {
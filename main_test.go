@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFixtures serves the quotes and rates from a temporary fixtures dir for
+// the duration of a test.
+func useFixtures(t *testing.T, quotes map[string]Quote, rates map[string]float64) {
+	dir := t.TempDir()
+	writeFixture(t, filepath.Join(dir, "quotes.yaml"), quotes)
+	writeFixture(t, filepath.Join(dir, "rates.yaml"), rates)
+
+	prevProvider, prevDir := provider, fixturesDir
+	provider, fixturesDir = fixtureProvider{}, dir
+	t.Cleanup(func() {
+		provider, fixturesDir = prevProvider, prevDir
+	})
+}
+
+func writeFixture(t *testing.T, path string, in interface{}) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, yamlToBytes(in), 0644)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+func assertFloat(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func fifo(string) string {
+	return costBasisFIFO
+}
+
+func TestGetInvestmentsStats(t *testing.T) {
+	useFixtures(t, map[string]Quote{
+		"AAPL": {Price: 180, Currency: "USD"},
+		"SAP":  {Price: 100, Currency: "EUR"},
+	}, map[string]float64{"EUR": 0.8})
+
+	investments := map[string][]Order{
+		"AAPL": {{Date: date("2021-01-04"), Units: 2, Price: 150, Fee: 1}},
+		"SAP":  {{Date: date("2021-02-01"), Units: 1, Price: 80}},
+	}
+
+	quotes := fetchQuotes([]string{"AAPL", "SAP"}, nil)
+	stats, err := getInvestmentsStats(investments, quotes, onQuoteErrorFail, fifo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertFloat(t, "Sum", stats.Sum, 360+125)
+	assertFloat(t, "In", stats.In, 301+100)
+	assertFloat(t, "Diff", stats.Diff, 59+25)
+	assertFloat(t, "Fees", stats.Fees, 1)
+	assertFloat(t, "SAP value", stats.Symbols["SAP"].Value, 125)
+	if stats.Symbols["SAP"].Currency != "EUR" {
+		t.Errorf("SAP currency = %q, want EUR", stats.Symbols["SAP"].Currency)
+	}
+}
+
+func TestGetInvestmentsStatsSkip(t *testing.T) {
+	useFixtures(t, map[string]Quote{
+		"AAPL": {Price: 180, Currency: "USD"},
+	}, map[string]float64{})
+
+	investments := map[string][]Order{
+		"AAPL": {{Units: 1, Price: 200}},
+		"MSFT": {{Units: 1, Price: 100}},
+	}
+
+	quotes := fetchQuotes([]string{"AAPL", "MSFT"}, nil)
+	stats, err := getInvestmentsStats(investments, quotes, onQuoteErrorSkip, fifo)
+	errs, ok := err.(QuoteErrors)
+	if !ok || len(errs) != 1 || errs["MSFT"] == nil {
+		t.Fatalf("err = %v, want a QuoteErrors of MSFT", err)
+	}
+
+	assertFloat(t, "Sum", stats.Sum, 180)
+	assertFloat(t, "Loss", stats.Loss, 20)
+}
+
+func TestPrintSumTable(t *testing.T) {
+	out := Out{
+		Currency:       "EUR",
+		Savings:        1000,
+		InvestmentsSum: 485,
+		Total:          1388,
+	}
+	out.Stocks.Sum = 485
+	out.Stocks.In = 401
+
+	s := captureStdout(t, func() {
+		printSumTable(out, [][]string{{"Savings (CHF)", "50.00"}})
+	})
+
+	for _, want := range []string{
+		"Savings (EUR)",
+		"1000.00",
+		"Savings (CHF)",
+		"Stocks Sum (USD)",
+		"485.00",
+		"Total (EUR)",
+		"1388.00",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("sum table misses %q:\n%s", want, s)
+		}
+	}
+
+	if strings.Contains(s, "Assets") {
+		t.Errorf("sum table has empty assets:\n%s", s)
+	}
+}
+
+func TestPrintSumGraph(t *testing.T) {
+	history := []Out{{Total: 100}, {Total: 120}}
+	history[1].Crypto.Sum = 20
+
+	captureStdout(t, func() {
+		if err := printSumGraph(history, []string{"total", " crypto.diff"}); err != nil {
+			t.Error(err)
+		}
+
+		if err := printSumGraph(history, []string{"crypto"}); err == nil {
+			t.Error("got no error for crypto")
+		}
+	})
+}
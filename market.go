@@ -1,7 +1,9 @@
 package main
 
 import (
-	"github.com/piquette/finance-go"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,10 +17,15 @@ type MarketConfig struct {
 
 // MarketInfo ...
 type MarketInfo struct {
-	DurationUntilOpen      *time.Duration
-	DurationUntilOpenPre   *time.Duration
-	DurationUntilClose     *time.Duration
-	DurationUntilClosePost *time.Duration
+	Holiday *MarketDay
+	Session *Session
+}
+
+// MarketDay is a holiday of a market, or an early close at CloseAt.
+type MarketDay struct {
+	Date    time.Time
+	Name    string
+	CloseAt string `yaml:"close_at"`
 }
 
 var markets = map[string]MarketConfig{
@@ -33,46 +40,135 @@ var markets = map[string]MarketConfig{
 	"jp_market": {"", "09:00 AM", "03:00 PM", ""},
 }
 
-func getMarketInfo(q finance.Quote) MarketInfo {
-	now := time.Now()
-	info := MarketInfo{}
+// SessionState ...
+type SessionState string
 
-	if conf, ok := markets[q.MarketID]; ok {
-		tz := q.ExchangeTimezoneName
+// States of a trading session. Closed is the time between the sessions of
+// two business days.
+const (
+	SessionPre     SessionState = "PRE"
+	SessionRegular SessionState = "REGULAR"
+	SessionPost    SessionState = "POST"
+	SessionClosed  SessionState = "CLOSED"
+)
 
-		if conf.OpenPreAt != "" {
-			duration := getDateAt(now, conf.OpenPreAt, tz).Sub(now)
-			info.DurationUntilOpenPre = &duration
-		}
+// Session is a trading session of a market from Start until End, when the
+// next session starts.
+type Session struct {
+	State  SessionState
+	Start  time.Time
+	End    time.Time
+	market string
+}
 
-		if conf.OpenAt != "" {
-			duration := getDateAt(now, conf.OpenAt, tz).Sub(now)
-			info.DurationUntilOpen = &duration
-		}
+// getSession returns the session of a market at t in the timezone of the
+// market, or false for unknown markets.
+func getSession(market string, tz string, t time.Time) (Session, bool) {
+	if _, ok := markets[market]; !ok {
+		return Session{}, false
+	}
 
-		if conf.CloseAt != "" {
-			duration := getDateAt(now, conf.CloseAt, tz).Sub(now)
-			info.DurationUntilClose = &duration
-		}
+	return sessionAt(market, t.In(getLocation(tz))), true
+}
 
-		if conf.ClosePostAt != "" {
-			duration := getDateAt(now, conf.ClosePostAt, tz).Sub(now)
-			info.DurationUntilClosePost = &duration
+func sessionAt(market string, t time.Time) Session {
+	sessions := []Session{}
+	if isBusinessDay(market, t) {
+		sessions = daySessions(market, t)
+	}
+
+	for _, s := range sessions {
+		if !t.Before(s.Start) && t.Before(s.End) {
+			return s
 		}
 	}
 
+	// Closed from the end of the last session until the start of the next.
+	closed := Session{State: SessionClosed, market: market}
+	if len(sessions) > 0 && t.Before(sessions[0].Start) {
+		closed.End = sessions[0].Start
+	} else {
+		closed.End = daySessions(market, getNextBusinessDay(market, t.AddDate(0, 0, 1)))[0].Start
+	}
+
+	if len(sessions) > 0 && !t.Before(sessions[len(sessions)-1].End) {
+		closed.Start = sessions[len(sessions)-1].End
+	} else {
+		prev := daySessions(market, getPrevBusinessDay(market, t.AddDate(0, 0, -1)))
+		closed.Start = prev[len(prev)-1].End
+	}
+
+	return closed
+}
+
+// Next returns the session starting at the end of s.
+func (s Session) Next() Session {
+	return sessionAt(s.market, s.End)
+}
+
+// daySessions returns the pre, regular and post sessions of a business day.
+// Markets without pre and post market only have a regular session.
+func daySessions(market string, day time.Time) []Session {
+	conf := markets[market]
+	closeAt := conf.CloseAt
+	if d, ok := getMarketDay(market, day); ok && d.CloseAt != "" {
+		closeAt = d.CloseAt
+	}
+
+	opens := getDateAt(day, conf.OpenAt, day.Location())
+	closes := getDateAt(day, closeAt, day.Location())
+	sessions := []Session{{State: SessionRegular, Start: opens, End: closes, market: market}}
+
+	if conf.OpenPreAt != "" {
+		pre := Session{State: SessionPre, Start: getDateAt(day, conf.OpenPreAt, day.Location()), End: opens, market: market}
+		sessions = append([]Session{pre}, sessions...)
+	}
+
+	if conf.ClosePostAt != "" {
+		post := Session{State: SessionPost, Start: closes, End: getDateAt(day, conf.ClosePostAt, day.Location()), market: market}
+		sessions = append(sessions, post)
+	}
+
+	return sessions
+}
+
+// getMarketInfo returns the current session of a market and its holiday or
+// early close of today. The time until the next transition is the end of the
+// session.
+func getMarketInfo(market string, tz string) MarketInfo {
+	now := time.Now()
+	info := MarketInfo{}
+
+	session, ok := getSession(market, tz, now)
+	if !ok {
+		return info
+	}
+
+	info.Session = &session
+	if day, ok := getMarketDay(market, now.In(session.End.Location())); ok {
+		info.Holiday = &day
+	}
+
 	return info
 }
 
-func getDateAt(ref time.Time, hour string, timezone string) time.Time {
-	loc, _ := time.LoadLocation(timezone)
+// getLocation returns the location of a timezone, or UTC if it is unknown.
+func getLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+func getDateAt(day time.Time, hour string, loc *time.Location) time.Time {
 	parsed, _ := time.ParseInLocation("03:04 PM", hour, loc)
-	ref = getNextBusinessDay(ref)
 
 	t := time.Date(
-		ref.Year(),
-		ref.Month(),
-		ref.Day(),
+		day.Year(),
+		day.Month(),
+		day.Day(),
 		parsed.Hour(),
 		parsed.Minute(),
 		parsed.Second(),
@@ -83,16 +179,387 @@ func getDateAt(ref time.Time, hour string, timezone string) time.Time {
 	return t
 }
 
-func getNextBusinessDay(t time.Time) time.Time {
+func getNextBusinessDay(market string, t time.Time) time.Time {
 	for {
-		if isBusinessDay(t) {
+		if isBusinessDay(market, t) {
 			return t
 		}
 
-		t = t.Add(time.Hour * 24)
+		t = t.AddDate(0, 0, 1)
+	}
+}
+
+func getPrevBusinessDay(market string, t time.Time) time.Time {
+	for {
+		if isBusinessDay(market, t) {
+			return t
+		}
+
+		t = t.AddDate(0, 0, -1)
+	}
+}
+
+// isBusinessDay returns false on weekends and holidays of a market. Days
+// with an early close are business days.
+func isBusinessDay(market string, t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+
+	day, ok := getMarketDay(market, t)
+	return !ok || day.CloseAt != ""
+}
+
+// calendars returns the built-in holidays and early closes of a market in a
+// year. Holidays of lunar calendars are not included and can be added in the
+// config.
+var calendars = map[string]func(year int) []MarketDay{
+	"us_market": nyseDays,
+	"de_market": xetraDays,
+	"gb_market": lseDays,
+	"fr_market": euronextDays,
+	"dk_market": copenhagenDays,
+	"au_market": asxDays,
+	"hk_market": hkexDays,
+	"cn_market": sseDays,
+	"jp_market": jpxDays,
+}
+
+// confMarketDays are the days of the markets in the config.
+var confMarketDays = map[string][]MarketDay{}
+
+func useMarkets(days map[string][]MarketDay) {
+	confMarketDays = days
+}
+
+func validateMarkets(days map[string][]MarketDay) error {
+	for market, marketDays := range days {
+		if _, ok := markets[market]; !ok {
+			return fmt.Errorf("Unknown market %q, allowed: %s", market, strings.Join(marketNames(), ","))
+		}
+
+		for _, day := range marketDays {
+			if day.Date.IsZero() {
+				return fmt.Errorf("%s: day without date", market)
+			}
+
+			if day.CloseAt != "" {
+				if _, err := time.Parse("03:04 PM", day.CloseAt); err != nil {
+					return fmt.Errorf("%s: invalid close_at %q, expected a time like 01:00 PM", market, day.CloseAt)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func marketNames() []string {
+	names := []string{}
+	for name := range markets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// getMarketDay returns the holiday or early close of a market at the date of
+// t. Days of the config take precedence over the built-in calendar.
+func getMarketDay(market string, t time.Time) (MarketDay, bool) {
+	date := t.Format("2006-01-02")
+	for _, day := range confMarketDays[market] {
+		if day.Date.Format("2006-01-02") == date {
+			return day, true
+		}
+	}
+
+	if calendar, ok := calendars[market]; ok {
+		for _, day := range calendar(t.Year()) {
+			if day.Date.Format("2006-01-02") == date {
+				return day, true
+			}
+		}
+	}
+
+	return MarketDay{}, false
+}
+
+func nyseDays(year int) []MarketDay {
+	days := []MarketDay{
+		{Date: nthWeekday(year, time.January, time.Monday, 3), Name: "Martin Luther King Jr. Day"},
+		{Date: nthWeekday(year, time.February, time.Monday, 3), Name: "Washington's Birthday"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: nthWeekday(year, time.May, time.Monday, -1), Name: "Memorial Day"},
+		{Date: observed(ymd(year, time.July, 4)), Name: "Independence Day"},
+		{Date: nthWeekday(year, time.September, time.Monday, 1), Name: "Labor Day"},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4), Name: "Thanksgiving Day"},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1), Name: "Day after Thanksgiving", CloseAt: "01:00 PM"},
+		{Date: observed(ymd(year, time.December, 25)), Name: "Christmas Day"},
+	}
+
+	// New Year's Day on a Saturday is not observed on the Friday before.
+	if newYear := ymd(year, time.January, 1); newYear.Weekday() != time.Saturday {
+		days = append(days, MarketDay{Date: observed(newYear), Name: "New Year's Day"})
+	}
+
+	if year >= 2022 {
+		days = append(days, MarketDay{Date: observed(ymd(year, time.June, 19)), Name: "Juneteenth"})
+	}
+
+	// The days before Independence Day and Christmas close early, unless
+	// the holiday is observed on them or they are a Friday.
+	for _, d := range []MarketDay{
+		{Date: ymd(year, time.July, 3), Name: "Independence Day Eve", CloseAt: "01:00 PM"},
+		{Date: ymd(year, time.December, 24), Name: "Christmas Eve", CloseAt: "01:00 PM"},
+	} {
+		if d.Date.Weekday() >= time.Monday && d.Date.Weekday() <= time.Thursday {
+			days = append(days, d)
+		}
+	}
+
+	return days
+}
+
+func xetraDays(year int) []MarketDay {
+	return []MarketDay{
+		{Date: ymd(year, time.January, 1), Name: "New Year's Day"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		{Date: ymd(year, time.May, 1), Name: "Labour Day"},
+		{Date: ymd(year, time.December, 24), Name: "Christmas Eve"},
+		{Date: ymd(year, time.December, 25), Name: "Christmas Day"},
+		{Date: ymd(year, time.December, 26), Name: "Boxing Day"},
+		{Date: ymd(year, time.December, 31), Name: "New Year's Eve"},
+	}
+}
+
+func lseDays(year int) []MarketDay {
+	return append(christmas(year, "12:30 PM"),
+		MarketDay{Date: substitute(ymd(year, time.January, 1)), Name: "New Year's Day"},
+		MarketDay{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		MarketDay{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		MarketDay{Date: nthWeekday(year, time.May, time.Monday, 1), Name: "Early May Bank Holiday"},
+		MarketDay{Date: nthWeekday(year, time.May, time.Monday, -1), Name: "Spring Bank Holiday"},
+		MarketDay{Date: nthWeekday(year, time.August, time.Monday, -1), Name: "Summer Bank Holiday"},
+	)
+}
+
+func euronextDays(year int) []MarketDay {
+	return []MarketDay{
+		{Date: ymd(year, time.January, 1), Name: "New Year's Day"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		{Date: ymd(year, time.May, 1), Name: "Labour Day"},
+		{Date: ymd(year, time.December, 24), Name: "Christmas Eve", CloseAt: "02:05 PM"},
+		{Date: ymd(year, time.December, 25), Name: "Christmas Day"},
+		{Date: ymd(year, time.December, 26), Name: "Boxing Day"},
+		{Date: ymd(year, time.December, 31), Name: "New Year's Eve", CloseAt: "02:05 PM"},
+	}
+}
+
+func copenhagenDays(year int) []MarketDay {
+	days := []MarketDay{
+		{Date: ymd(year, time.January, 1), Name: "New Year's Day"},
+		{Date: easter(year).AddDate(0, 0, -3), Name: "Maundy Thursday"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		{Date: easter(year).AddDate(0, 0, 39), Name: "Ascension Day"},
+		{Date: easter(year).AddDate(0, 0, 40), Name: "Day after Ascension Day"},
+		{Date: easter(year).AddDate(0, 0, 50), Name: "Whit Monday"},
+		{Date: ymd(year, time.June, 5), Name: "Constitution Day"},
+		{Date: ymd(year, time.December, 24), Name: "Christmas Eve"},
+		{Date: ymd(year, time.December, 25), Name: "Christmas Day"},
+		{Date: ymd(year, time.December, 26), Name: "Boxing Day"},
+		{Date: ymd(year, time.December, 31), Name: "New Year's Eve"},
+	}
+
+	if year < 2024 {
+		days = append(days, MarketDay{Date: easter(year).AddDate(0, 0, 26), Name: "Great Prayer Day"})
 	}
+
+	return days
+}
+
+func asxDays(year int) []MarketDay {
+	return append(christmas(year, "02:10 PM"),
+		MarketDay{Date: substitute(ymd(year, time.January, 1)), Name: "New Year's Day"},
+		MarketDay{Date: substitute(ymd(year, time.January, 26)), Name: "Australia Day"},
+		MarketDay{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		MarketDay{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		MarketDay{Date: ymd(year, time.April, 25), Name: "Anzac Day"},
+		MarketDay{Date: nthWeekday(year, time.June, time.Monday, 2), Name: "King's Birthday"},
+	)
+}
+
+func hkexDays(year int) []MarketDay {
+	return append(christmas(year, "12:00 PM"),
+		MarketDay{Date: substitute(ymd(year, time.January, 1)), Name: "New Year's Day"},
+		MarketDay{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		MarketDay{Date: easter(year).AddDate(0, 0, 1), Name: "Easter Monday"},
+		MarketDay{Date: substitute(ymd(year, time.May, 1)), Name: "Labour Day"},
+		MarketDay{Date: substitute(ymd(year, time.July, 1)), Name: "HKSAR Establishment Day"},
+		MarketDay{Date: substitute(ymd(year, time.October, 1)), Name: "National Day"},
+	)
+}
+
+func sseDays(year int) []MarketDay {
+	days := []MarketDay{
+		{Date: ymd(year, time.January, 1), Name: "New Year's Day"},
+		{Date: ymd(year, time.May, 1), Name: "Labour Day"},
+	}
+
+	for i := 1; i <= 7; i++ {
+		days = append(days, MarketDay{Date: ymd(year, time.October, i), Name: "National Day"})
+	}
+
+	return days
+}
+
+// jpxDays returns the national holidays of Japan with their substitute
+// holidays, and the market holidays at the turn of the year.
+func jpxDays(year int) []MarketDay {
+	holidays := []MarketDay{
+		{Date: ymd(year, time.January, 1), Name: "New Year's Day"},
+		{Date: nthWeekday(year, time.January, time.Monday, 2), Name: "Coming of Age Day"},
+		{Date: ymd(year, time.February, 11), Name: "National Foundation Day"},
+		{Date: ymd(year, time.February, 23), Name: "Emperor's Birthday"},
+		{Date: ymd(year, time.March, equinox(year, 20.8431)), Name: "Vernal Equinox Day"},
+		{Date: ymd(year, time.April, 29), Name: "Showa Day"},
+		{Date: ymd(year, time.May, 3), Name: "Constitution Memorial Day"},
+		{Date: ymd(year, time.May, 4), Name: "Greenery Day"},
+		{Date: ymd(year, time.May, 5), Name: "Children's Day"},
+		{Date: nthWeekday(year, time.July, time.Monday, 3), Name: "Marine Day"},
+		{Date: ymd(year, time.August, 11), Name: "Mountain Day"},
+		{Date: nthWeekday(year, time.September, time.Monday, 3), Name: "Respect for the Aged Day"},
+		{Date: ymd(year, time.September, equinox(year, 23.2488)), Name: "Autumnal Equinox Day"},
+		{Date: nthWeekday(year, time.October, time.Monday, 2), Name: "Sports Day"},
+		{Date: ymd(year, time.November, 3), Name: "Culture Day"},
+		{Date: ymd(year, time.November, 23), Name: "Labour Thanksgiving Day"},
+	}
+
+	days := append(holidays, jpSubstitutes(holidays)...)
+	return append(days,
+		MarketDay{Date: ymd(year, time.January, 2), Name: "Market Holiday"},
+		MarketDay{Date: ymd(year, time.January, 3), Name: "Market Holiday"},
+		MarketDay{Date: ymd(year, time.December, 31), Name: "Market Holiday"},
+	)
+}
+
+// jpSubstitutes returns the substitute holidays of Japan. A holiday on a
+// Sunday moves to the next day which is no holiday, and a day between two
+// holidays is a holiday too.
+func jpSubstitutes(holidays []MarketDay) []MarketDay {
+	isHoliday := map[time.Time]bool{}
+	for _, h := range holidays {
+		isHoliday[h.Date] = true
+	}
+
+	days := []MarketDay{}
+	for _, h := range holidays {
+		next := h.Date.AddDate(0, 0, 1)
+		if isHoliday[h.Date.AddDate(0, 0, 2)] && !isHoliday[next] && next.Weekday() != time.Sunday {
+			days = append(days, MarketDay{Date: next, Name: "Citizens' Holiday"})
+		}
+	}
+
+	for _, d := range days {
+		isHoliday[d.Date] = true
+	}
+
+	for _, h := range holidays {
+		if h.Date.Weekday() != time.Sunday {
+			continue
+		}
+
+		t := h.Date.AddDate(0, 0, 1)
+		for isHoliday[t] {
+			t = t.AddDate(0, 0, 1)
+		}
+
+		isHoliday[t] = true
+		days = append(days, MarketDay{Date: t, Name: "Substitute Holiday"})
+	}
+
+	return days
+}
+
+// equinox returns the day of the vernal (base 20.8431) or autumnal (base
+// 23.2488) equinox in Japan, valid from 1980 to 2099.
+func equinox(year int, base float64) int {
+	y := year - 1980
+	return int(base + 0.242194*float64(y) - float64(y/4))
+}
+
+// christmas returns Christmas and Boxing Day, moved to the next weekdays if
+// on a weekend, and early closes on Christmas Eve and New Year's Eve.
+func christmas(year int, closeAt string) []MarketDay {
+	christmasDay := substitute(ymd(year, time.December, 25))
+	boxingDay := ymd(year, time.December, 26)
+	for boxingDay.Weekday() == time.Saturday || boxingDay.Weekday() == time.Sunday || boxingDay.Equal(christmasDay) {
+		boxingDay = boxingDay.AddDate(0, 0, 1)
+	}
+
+	return []MarketDay{
+		{Date: ymd(year, time.December, 24), Name: "Christmas Eve", CloseAt: closeAt},
+		{Date: christmasDay, Name: "Christmas Day"},
+		{Date: boxingDay, Name: "Boxing Day"},
+		{Date: ymd(year, time.December, 31), Name: "New Year's Eve", CloseAt: closeAt},
+	}
+}
+
+func ymd(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// observed moves a holiday on a Saturday to the Friday before and on a
+// Sunday to the Monday after.
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+
+	return t
+}
+
+// substitute moves a holiday on a weekend to the Monday after.
+func substitute(t time.Time) time.Time {
+	for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t
+}
+
+// nthWeekday returns the nth weekday of a month, or the last for -1.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		t := ymd(year, month+1, 0)
+		for t.Weekday() != weekday {
+			t = t.AddDate(0, 0, -1)
+		}
+
+		return t
+	}
+
+	t := ymd(year, month, 1)
+	for t.Weekday() != weekday {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t.AddDate(0, 0, 7*(n-1))
 }
 
-func isBusinessDay(t time.Time) bool {
-	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+// easter returns Easter Sunday of the Gregorian calendar.
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := (19*a + b - b/4 - (b-(b+8)/25+1)/3 + 15) % 30
+	e := (32 + 2*(b%4) + 2*(c/4) - d - c%4) % 7
+	f := d + e - 7*((a+11*d+22*e)/451) + 114
+	return ymd(year, time.Month(f/31), f%31+1)
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetMarketDay(t *testing.T) {
+	tests := []struct {
+		market  string
+		date    string
+		name    string
+		closeAt string
+	}{
+		{"us_market", "2022-12-26", "Christmas Day", ""},
+		{"us_market", "2021-12-31", "", ""},
+		{"us_market", "2023-07-03", "Independence Day Eve", "01:00 PM"},
+		{"us_market", "2024-11-29", "Day after Thanksgiving", "01:00 PM"},
+		{"us_market", "2025-04-18", "Good Friday", ""},
+		{"de_market", "2025-04-21", "Easter Monday", ""},
+		{"gb_market", "2021-12-28", "Boxing Day", ""},
+		{"dk_market", "2023-05-05", "Great Prayer Day", ""},
+		{"dk_market", "2024-04-26", "", ""},
+		{"jp_market", "2026-03-20", "Vernal Equinox Day", ""},
+		{"jp_market", "2026-05-06", "Substitute Holiday", ""},
+		{"jp_market", "2026-09-22", "Citizens' Holiday", ""},
+		{"jp_market", "2026-09-23", "Autumnal Equinox Day", ""},
+		{"jp_market", "2027-03-22", "Substitute Holiday", ""},
+	}
+
+	for _, test := range tests {
+		day, ok := getMarketDay(test.market, date(test.date))
+		if ok != (test.name != "") || day.Name != test.name || day.CloseAt != test.closeAt {
+			t.Errorf("%s %s: got %q %q, want %q %q", test.market, test.date, day.Name, day.CloseAt, test.name, test.closeAt)
+		}
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	useMarkets(map[string][]MarketDay{
+		"hk_market": {{Date: date("2026-02-17"), Name: "Lunar New Year"}},
+		"us_market": {{Date: date("2026-12-31"), Name: "New Year's Eve", CloseAt: "01:00 PM"}},
+	})
+	defer useMarkets(map[string][]MarketDay{})
+
+	tests := []struct {
+		market string
+		date   string
+		want   bool
+	}{
+		{"us_market", "2026-10-17", false},
+		{"us_market", "2026-11-26", false},
+		{"us_market", "2026-11-27", true},
+		{"us_market", "2026-12-31", true},
+		{"hk_market", "2026-02-17", false},
+		{"hk_market", "2026-02-16", true},
+	}
+
+	for _, test := range tests {
+		if got := isBusinessDay(test.market, date(test.date)); got != test.want {
+			t.Errorf("%s %s: got %v, want %v", test.market, test.date, got, test.want)
+		}
+	}
+
+	day, _ := getMarketDay("us_market", date("2026-12-31"))
+	if day.CloseAt != "01:00 PM" {
+		t.Errorf("config day has close %q, want 01:00 PM", day.CloseAt)
+	}
+}
+
+func TestEaster(t *testing.T) {
+	for year, want := range map[int]string{2021: "2021-04-04", 2024: "2024-03-31", 2026: "2026-04-05"} {
+		if got := easter(year).Format("2006-01-02"); got != want {
+			t.Errorf("easter(%d) = %s, want %s", year, got, want)
+		}
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	tests := []struct {
+		market string
+		tz     string
+		at     string
+		want   []string
+	}{
+		{"us_market", "America/New_York", "2026-10-16 17:00", []string{
+			"POST 2026-10-16 16:00 2026-10-16 20:00",
+			"CLOSED 2026-10-16 20:00 2026-10-19 04:00",
+			"PRE 2026-10-19 04:00 2026-10-19 09:30",
+			"REGULAR 2026-10-19 09:30 2026-10-19 16:00",
+		}},
+		{"us_market", "America/New_York", "2026-11-26 12:00", []string{
+			"CLOSED 2026-11-25 20:00 2026-11-27 04:00",
+			"PRE 2026-11-27 04:00 2026-11-27 09:30",
+			"REGULAR 2026-11-27 09:30 2026-11-27 13:00",
+			"POST 2026-11-27 13:00 2026-11-27 20:00",
+		}},
+		{"jp_market", "Asia/Tokyo", "2026-05-06 10:00", []string{
+			"CLOSED 2026-05-01 15:00 2026-05-07 09:00",
+			"REGULAR 2026-05-07 09:00 2026-05-07 15:00",
+			"CLOSED 2026-05-07 15:00 2026-05-08 09:00",
+		}},
+	}
+
+	for _, test := range tests {
+		at, err := time.ParseInLocation("2006-01-02 15:04", test.at, getLocation(test.tz))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, ok := getSession(test.market, test.tz, at)
+		if !ok {
+			t.Fatalf("%s: no session", test.market)
+		}
+
+		for i, want := range test.want {
+			got := string(s.State) + " " + s.Start.Format("2006-01-02 15:04") + " " + s.End.Format("2006-01-02 15:04")
+			if got != want {
+				t.Errorf("%s %s session %d: got %s, want %s", test.market, test.at, i, got, want)
+			}
+
+			s = s.Next()
+		}
+	}
+
+	if _, ok := getSession("xx_market", "UTC", time.Now()); ok {
+		t.Error("got a session of an unknown market")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics serves the stats of the last refresh in the Prometheus text format.
+type Metrics struct {
+	mu   sync.Mutex
+	text []byte
+}
+
+// gauge is a metric with its samples, each a label string and value.
+type gauge struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+// update replaces the metrics by the stats of out.
+func (m *Metrics) update(out Out) {
+	refreshed := gauge{name: "fin_stats_last_refresh_timestamp_seconds", help: "Time of the last successful refresh."}
+	refreshed.samples = []sample{{"", float64(out.Date.Unix())}}
+	text := formatMetrics(append(getGauges(out), refreshed))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.text == nil {
+		http.Error(w, "Metrics not available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(m.text)
+}
+
+// getGauges returns the gauges of an Out. Total and savings are in the config
+// currency, investments in USD.
+func getGauges(out Out) []gauge {
+	currency := out.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	// Investments are in USD, the total and savings in the output currency.
+	total := gauge{name: "fin_stats_total", help: "Total of savings and investments."}
+	total.samples = []sample{{formatLabels("currency", currency), out.Total}}
+	savings := gauge{name: "fin_stats_savings", help: "Sum of all savings."}
+	savings.samples = []sample{{formatLabels("currency", currency), out.Savings}}
+
+	sum := gauge{name: "fin_stats_investments_sum", help: "Current value of the investments of a category."}
+	in := gauge{name: "fin_stats_investments_in", help: "Cost basis of the investments of a category."}
+	diff := gauge{name: "fin_stats_investments_diff", help: "Unrealized gain of the investments of a category."}
+	loss := gauge{name: "fin_stats_investments_loss", help: "Unrealized loss of the investments of a category."}
+	price := gauge{name: "fin_stats_symbol_price", help: "Quote price of a symbol."}
+	pct := gauge{name: "fin_stats_symbol_change_percent", help: "Change percent of a symbol."}
+	units := gauge{name: "fin_stats_symbol_units", help: "Open units of a symbol."}
+	value := gauge{name: "fin_stats_symbol_value", help: "Current value of the open units of a symbol."}
+
+	categories := []struct {
+		name  string
+		stats InvestmentStats
+	}{
+		{"stocks", out.Stocks},
+		{"assets", out.Assets},
+		{"crypto", out.Crypto},
+	}
+
+	for _, c := range categories {
+		labels := formatLabels("category", c.name, "currency", "USD")
+		sum.samples = append(sum.samples, sample{labels, c.stats.Sum})
+		in.samples = append(in.samples, sample{labels, c.stats.In})
+		diff.samples = append(diff.samples, sample{labels, c.stats.Diff})
+		loss.samples = append(loss.samples, sample{labels, c.stats.Loss})
+
+		symbols := []string{}
+		for symbol := range c.stats.Details {
+			symbols = append(symbols, symbol)
+		}
+
+		sort.Strings(symbols)
+		for _, symbol := range symbols {
+			details := c.stats.Details[symbol]
+			labels := formatLabels("category", c.name, "symbol", symbol)
+			u := 0.0
+			v := 0.0
+			for _, d := range details {
+				u = u + d.Units
+				v = v + d.Sum
+			}
+
+			quote := details[0].Quote
+			quoteCurrency := quote.Currency
+			if quoteCurrency == "" {
+				quoteCurrency = "USD"
+			}
+
+			price.samples = append(price.samples, sample{formatLabels("category", c.name, "symbol", symbol, "currency", quoteCurrency), quote.Price})
+			pct.samples = append(pct.samples, sample{labels, quote.Pct})
+			units.samples = append(units.samples, sample{labels, u})
+			value.samples = append(value.samples, sample{formatLabels("category", c.name, "symbol", symbol, "currency", "USD"), v})
+		}
+	}
+
+	return []gauge{total, savings, sum, in, diff, loss, price, pct, units, value}
+}
+
+func formatMetrics(gauges []gauge) []byte {
+	var buf bytes.Buffer
+	for _, g := range gauges {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", g.name)
+		for _, s := range g.samples {
+			fmt.Fprintf(&buf, "%s%s %s\n", g.name, s.labels, strconv.FormatFloat(s.value, 'f', -1, 64))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// formatLabels formats pairs of label names and values like {a="1",b="2"}.
+func formatLabels(pairs ...string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	labels := []string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], replacer.Replace(pairs[i+1])))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
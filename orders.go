@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	sideBuy  = "buy"
+	sideSell = "sell"
+)
+
+// Cost basis methods to match sells to lots.
+const (
+	costBasisFIFO    = "fifo"
+	costBasisLIFO    = "lifo"
+	costBasisAverage = "average"
+)
+
+// Sale ...
+type Sale struct {
+	Date     time.Time
+	BoughtAt time.Time `yaml:"bought_at"`
+	Units    float64
+	Proceeds float64
+	Cost     float64
+	Gain     float64
+	// Fees is the share of the buy and sell fees included in the gain.
+	Fees float64
+}
+
+// lot is an open position of a buy order. In is the remaining cost basis
+// including the remaining fee, converted to USD.
+type lot struct {
+	InvestmentDetail
+	fee float64
+}
+
+// unitPrice returns the price per unit of an order. In is the price of buy
+// orders written before orders had a side.
+func (o Order) unitPrice() float64 {
+	if o.Price != 0 {
+		return o.Price
+	}
+
+	return o.In
+}
+
+// sortOrders returns the orders sorted by date. Orders without a date keep
+// their order and come first.
+func sortOrders(orders []Order) []Order {
+	sorted := append([]Order{}, orders...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	return sorted
+}
+
+// costBasis returns the cost basis method of a symbol, default is fifo.
+func (c *Conf) costBasis(symbol string) string {
+	if method, ok := c.CostBasisSymbols[symbol]; ok {
+		return method
+	}
+
+	if c.CostBasis != "" {
+		return c.CostBasis
+	}
+
+	return costBasisFIFO
+}
+
+// matchLots applies the sell orders of a symbol to its buy orders. Prices
+// and fees of an order are divided by its factor. It returns all buy lots
+// with their remaining units, the sales and the fees of all orders.
+func matchLots(orders []Order, factor func(Order) float64, method string) ([]lot, []Sale, float64) {
+	lots := []lot{}
+	sales := []Sale{}
+	fees := 0.0
+
+	for _, order := range sortOrders(orders) {
+		f := factor(order)
+		price := order.unitPrice() / f
+		fee := order.Fee / f
+		fees = fees + fee
+
+		if order.Side == sideSell {
+			sales = append(sales, sellLots(lots, order, price, fee, method)...)
+			continue
+		}
+
+		lots = append(lots, lot{
+			InvestmentDetail: InvestmentDetail{
+				Date:  order.Date,
+				Units: order.Units,
+				In:    order.Units*price + fee,
+			},
+			fee: fee,
+		})
+	}
+
+	return lots, sales, fees
+}
+
+// sellLots removes the units of a sell order from the lots. fifo sells the
+// oldest lots first, lifo the newest and average sells the same share of
+// every lot at the average cost.
+func sellLots(lots []lot, order Order, price float64, fee float64, method string) []Sale {
+	sales := []Sale{}
+
+	if method == costBasisAverage {
+		open := 0.0
+		for _, l := range lots {
+			open = open + l.Units
+		}
+
+		for i := range lots {
+			if lots[i].Units > 0 {
+				sales = append(sales, sellLot(&lots[i], lots[i].Units*order.Units/open, order, price, fee))
+			}
+		}
+
+		return sales
+	}
+
+	indexes := make([]int, len(lots))
+	for i := range lots {
+		indexes[i] = i
+		if method == costBasisLIFO {
+			indexes[i] = len(lots) - 1 - i
+		}
+	}
+
+	left := order.Units
+	for _, i := range indexes {
+		if left <= 0 {
+			break
+		}
+
+		if lots[i].Units <= 0 {
+			continue
+		}
+
+		sold := math.Min(left, lots[i].Units)
+		sales = append(sales, sellLot(&lots[i], sold, order, price, fee))
+		left = left - sold
+	}
+
+	return sales
+}
+
+// sellLot sells units of a lot as part of a sell order. The order fee is
+// split by the share of sold units.
+func sellLot(l *lot, sold float64, order Order, price float64, fee float64) Sale {
+	cost := l.In * sold / l.Units
+	buyFee := l.fee * sold / l.Units
+	sellFee := fee * sold / order.Units
+	proceeds := sold*price - sellFee
+
+	l.Units = l.Units - sold
+	l.In = l.In - cost
+	l.fee = l.fee - buyFee
+	l.Realized = l.Realized + proceeds - cost
+
+	return Sale{
+		Date:     order.Date,
+		BoughtAt: l.Date,
+		Units:    sold,
+		Proceeds: proceeds,
+		Cost:     cost,
+		Gain:     proceeds - cost,
+		Fees:     buyFee + sellFee,
+	}
+}
+
+func realizedGain(sales []Sale) float64 {
+	gain := 0.0
+	for _, sale := range sales {
+		gain = gain + sale.Gain
+	}
+
+	return gain
+}
+
+func holdingDays(date time.Time, now time.Time) int {
+	return int(now.Sub(date).Hours() / 24)
+}
+
+// isLongTerm reports whether a lot bought at date was held for more than a
+// year at now.
+func isLongTerm(date time.Time, now time.Time) bool {
+	return now.After(date.AddDate(1, 0, 0))
+}
+
+func validateOrders(c *Conf) error {
+	methods := []string{c.CostBasis}
+	for _, method := range c.CostBasisSymbols {
+		methods = append(methods, method)
+	}
+
+	for _, method := range methods {
+		switch method {
+		case "", costBasisFIFO, costBasisLIFO, costBasisAverage:
+		default:
+			return fmt.Errorf("unknown cost basis %q, allowed: fifo,lifo,average", method)
+		}
+	}
+
+	for _, investments := range c.investmentOrders() {
+		for symbol, orders := range investments {
+			units := 0.0
+			for _, order := range sortOrders(orders) {
+				switch order.Side {
+				case "", sideBuy:
+					units = units + order.Units
+				case sideSell:
+					if order.Price <= 0 {
+						return fmt.Errorf("%s: sell order without price", symbol)
+					}
+
+					units = units - order.Units
+					if units < -1e-9 {
+						return fmt.Errorf("%s: sell of %v units exceeds open units", symbol, order.Units)
+					}
+				default:
+					return fmt.Errorf("%s: unknown order side %q, allowed: buy,sell", symbol, order.Side)
+				}
+			}
+		}
+	}
+
+	return nil
+}
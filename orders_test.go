@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func noFactor(Order) float64 {
+	return 1
+}
+
+func TestMatchLots(t *testing.T) {
+	orders := []Order{
+		{Date: date("2021-03-01"), Side: sideSell, Units: 3, Price: 30, Fee: 3},
+		{Date: date("2021-01-01"), Units: 2, Price: 10, Fee: 2},
+		{Date: date("2021-02-01"), Units: 2, Price: 20},
+	}
+
+	tests := []struct {
+		method string
+		units  []float64
+		gain   float64
+	}{
+		{costBasisFIFO, []float64{0, 1}, 90 - 3 - 22 - 20},
+		{costBasisLIFO, []float64{1, 0}, 90 - 3 - 40 - 11},
+		{costBasisAverage, []float64{0.5, 0.5}, 90 - 3 - 16.5 - 30},
+	}
+
+	for _, test := range tests {
+		lots, sales, fees := matchLots(orders, noFactor, test.method)
+		if len(lots) != 2 {
+			t.Fatalf("%s: got %d lots, want 2", test.method, len(lots))
+		}
+
+		for i, units := range test.units {
+			assertFloat(t, test.method+" units", lots[i].Units, units)
+		}
+
+		assertFloat(t, test.method+" gain", realizedGain(sales), test.gain)
+		assertFloat(t, test.method+" fees", fees, 5)
+	}
+}
+
+func TestMatchLotsFactor(t *testing.T) {
+	orders := []Order{
+		{Units: 1, In: 90, Currency: "EUR"},
+		{Units: 1, Price: 100},
+	}
+
+	factor := func(o Order) float64 {
+		if o.Currency == "EUR" {
+			return 0.9
+		}
+
+		return 1
+	}
+
+	lots, sales, _ := matchLots(orders, factor, costBasisFIFO)
+	if len(sales) != 0 {
+		t.Errorf("got %d sales, want none", len(sales))
+	}
+
+	assertFloat(t, "EUR lot in", lots[0].In, 100)
+	assertFloat(t, "USD lot in", lots[1].In, 100)
+}
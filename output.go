@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"os"
+)
+
+// Output formats of the global output flag.
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputCSV      = "csv"
+	outputMarkdown = "markdown"
+	outputYAML     = "yaml"
+)
+
+var outputFormat = outputTable
+
+// Table collects the rows of a command output. It is rendered as table, csv
+// or markdown, or the data behind the rows is serialized as json or yaml.
+type Table struct {
+	headers []string
+	footer  []string
+	rows    [][]string
+	colors  [][]tablewriter.Colors
+}
+
+func newTable() *Table {
+	return &Table{}
+}
+
+// SetHeader ...
+func (t *Table) SetHeader(headers []string) {
+	t.headers = headers
+}
+
+// SetFooter ...
+func (t *Table) SetFooter(footer []string) {
+	t.footer = footer
+}
+
+// Append ...
+func (t *Table) Append(row []string) {
+	t.Rich(row, nil)
+}
+
+// Rich ...
+func (t *Table) Rich(row []string, colors []tablewriter.Colors) {
+	t.rows = append(t.rows, row)
+	t.colors = append(t.colors, colors)
+}
+
+// Render prints the table in the output format, data is used for the
+// structured formats.
+func (t *Table) Render(data interface{}) {
+	switch outputFormat {
+	case outputJSON, outputYAML:
+		printData(data)
+	case outputCSV:
+		w := csv.NewWriter(os.Stdout)
+		if len(t.headers) > 0 {
+			w.Write(t.headers)
+		}
+
+		w.WriteAll(t.rows)
+		if len(t.footer) > 0 {
+			w.Write(t.footer)
+		}
+
+		w.Flush()
+	case outputMarkdown:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		table.SetCenterSeparator("|")
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+		table.SetHeader(t.headers)
+		table.AppendBulk(t.rows)
+		if len(t.footer) > 0 {
+			table.Append(t.footer)
+		}
+
+		table.Render()
+		fmt.Println("")
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		if len(t.headers) > 0 {
+			table.SetHeader(t.headers)
+		}
+
+		color := isTerminal(os.Stdout)
+		for i, row := range t.rows {
+			if color && t.colors[i] != nil {
+				table.Rich(row, t.colors[i])
+			} else {
+				table.Append(row)
+			}
+		}
+
+		if len(t.footer) > 0 {
+			table.SetFooter(t.footer)
+		}
+
+		table.Render()
+	}
+}
+
+// printData serializes data as json or yaml.
+func printData(data interface{}) {
+	if outputFormat == outputYAML {
+		os.Stdout.Write(yamlToBytes(data))
+		return
+	}
+
+	buf, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	fmt.Println(string(buf))
+}
+
+func isStructuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// notes returns the writer for hints around the output like stale quotes,
+// which must not end up in machine readable output.
+func notes() io.Writer {
+	if outputFormat == outputTable {
+		return os.Stdout
+	}
+
+	return os.Stderr
+}
+
+// clearScreen clears the terminal before the next tick of watch modes.
+func clearScreen() {
+	if outputFormat == outputTable && isTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func useOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputCSV, outputMarkdown, outputYAML:
+		outputFormat = format
+		return nil
+	}
+
+	return fmt.Errorf("Unknown output %q, allowed: table,json,csv,markdown,yaml", format)
+}
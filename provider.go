@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// QuoteProvider ...
+type QuoteProvider interface {
+	// Quote returns the latest quote of a symbol.
+	Quote(symbol string) (Quote, error)
+	// Chart returns the historical bars of a symbol.
+	Chart(symbol string, params ChartParams) ([]Bar, error)
+	// Rate returns the units of a currency per USD.
+	Rate(currency string) (float64, error)
+	// RateChart returns the daily units of a currency per USD, each bar at
+	// midnight UTC of its date.
+	RateChart(currency string, params ChartParams) ([]Bar, error)
+}
+
+// ChartParams ...
+type ChartParams struct {
+	Start    time.Time
+	End      time.Time
+	Interval string
+}
+
+// Bar ...
+type Bar struct {
+	Time  time.Time
+	Close float64
+}
+
+var providers = map[string]func() QuoteProvider{
+	"yahoo":   func() QuoteProvider { return yahooProvider{} },
+	"fixture": func() QuoteProvider { return fixtureProvider{} },
+}
+
+var provider QuoteProvider = yahooProvider{}
+
+// providerFlag is set if the provider was passed on the command line and
+// takes precedence over the provider in the config.
+var providerFlag bool
+
+func useProvider(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	create, ok := providers[name]
+	if !ok {
+		return fmt.Errorf("Unknown provider %q, allowed: %s", name, strings.Join(providerNames(), ","))
+	}
+
+	provider = create()
+	return nil
+}
+
+// loadProvider selects the provider of the default config file, for
+// commands without a config flag.
+func loadProvider() error {
+	_, _, err := loadConf("")
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func providerNames() []string {
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
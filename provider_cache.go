@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is the quote cache TTL if the config has none. It only
+// saves requests within one refresh, and is shorter than the 2s tick of
+// quote --watch, so every tick fetches new quotes.
+const defaultCacheTTL = time.Second
+
+// CacheConf ...
+type CacheConf struct {
+	// TTL is the max age of a cached quote before it is fetched again,
+	// default is defaultCacheTTL.
+	TTL      time.Duration
+	Disabled bool
+}
+
+// StaleError is returned with a cached rate if the wrapped provider failed.
+type StaleError struct {
+	Since time.Time
+	Err   error
+}
+
+func (e StaleError) Error() string {
+	return fmt.Sprintf("stale since %s: %v", e.Since.Format("2006-01-02 15:04"), e.Err)
+}
+
+type cacheEntry struct {
+	Quote Quote `yaml:",omitempty"`
+	Rate  float64
+	Time  time.Time
+}
+
+type cacheData struct {
+	Quotes map[string]cacheEntry
+	Rates  map[string]cacheEntry
+}
+
+// cachedProvider wraps a provider and stores every quote and rate on disk,
+// or only in memory without path. Entries younger than the TTL are served
+// without a request, older entries are served with a stale marker if the
+// wrapped provider fails: StaleSince for quotes and a StaleError for rates.
+// New entries are written to disk by flush.
+type cachedProvider struct {
+	QuoteProvider
+	ttl   time.Duration
+	path  string
+	mu    sync.Mutex
+	data  cacheData
+	dirty bool
+}
+
+func newCachedProvider(p QuoteProvider, ttl time.Duration, path string) *cachedProvider {
+	cp := &cachedProvider{QuoteProvider: p, ttl: ttl, path: path}
+	if _, err := os.Stat(path); err == nil {
+		if err := readYaml(path, &cp.data); err != nil {
+			log.Println("Ignoring quote cache:", err)
+		}
+	}
+
+	if cp.data.Quotes == nil {
+		cp.data.Quotes = make(map[string]cacheEntry)
+	}
+
+	if cp.data.Rates == nil {
+		cp.data.Rates = make(map[string]cacheEntry)
+	}
+
+	return cp
+}
+
+func (p *cachedProvider) Quote(symbol string) (Quote, error) {
+	p.mu.Lock()
+	entry, ok := p.data.Quotes[symbol]
+	p.mu.Unlock()
+
+	if ok && time.Since(entry.Time) < p.ttl {
+		return entry.Quote, nil
+	}
+
+	q, err := p.QuoteProvider.Quote(symbol)
+	if err != nil {
+		if ok {
+			stale := entry.Time
+			entry.Quote.StaleSince = &stale
+			return entry.Quote, nil
+		}
+
+		return q, err
+	}
+
+	p.store(func() {
+		p.data.Quotes[symbol] = cacheEntry{Quote: q, Time: time.Now()}
+	})
+
+	return q, nil
+}
+
+func (p *cachedProvider) Rate(currency string) (float64, error) {
+	p.mu.Lock()
+	entry, ok := p.data.Rates[currency]
+	p.mu.Unlock()
+
+	if ok && time.Since(entry.Time) < p.ttl {
+		return entry.Rate, nil
+	}
+
+	rate, err := p.QuoteProvider.Rate(currency)
+	if err != nil || rate == 0 {
+		if ok {
+			if err == nil {
+				err = fmt.Errorf("Rate is zero")
+			}
+
+			return entry.Rate, StaleError{Since: entry.Time, Err: err}
+		}
+
+		return rate, err
+	}
+
+	p.store(func() {
+		p.data.Rates[currency] = cacheEntry{Rate: rate, Time: time.Now()}
+	})
+
+	return rate, nil
+}
+
+func (p *cachedProvider) store(update func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	update()
+	p.dirty = true
+}
+
+// flush writes the cache file if entries were added since the last flush.
+// The file is replaced, so other processes never read a partial file.
+func (p *cachedProvider) flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.path == "" || !p.dirty {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(p.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = replaceFile(p.path, yamlToBytes(&p.data))
+	if err == nil {
+		p.dirty = false
+	}
+
+	return err
+}
+
+// flushCache writes the quotes and rates fetched by a batch to the cache
+// file of the provider, if it has one.
+func flushCache() {
+	if cp, ok := provider.(*cachedProvider); ok {
+		if err := cp.flush(); err != nil {
+			log.Println("Could not write quote cache:", err)
+		}
+	}
+}
+
+// useCache wraps the current provider with a cache in the data dir of the
+// config file. Nothing is cached if the data dir does not exist, and the
+// fixture provider is never cached, so fixture prices cannot be served to
+// online runs.
+func useCache(conf CacheConf, configFile string) {
+	if cp, ok := provider.(*cachedProvider); ok {
+		provider = cp.QuoteProvider
+	}
+
+	if _, ok := provider.(fixtureProvider); ok || conf.Disabled {
+		return
+	}
+
+	if conf.TTL == 0 {
+		conf.TTL = defaultCacheTTL
+	}
+
+	dir, err := getOutDir(configFile)
+	if err != nil {
+		return
+	}
+
+	provider = newCachedProvider(provider, conf.TTL, filepath.Join(dir, "cache", "quotes.yaml"))
+}
+
+// useSharedCache is useCache for long running commands. Quotes are kept
+// only in memory if the data dir does not exist.
+func useSharedCache(conf CacheConf, configFile string) {
+	useCache(conf, configFile)
+	if _, ok := provider.(*cachedProvider); ok || conf.Disabled {
+		return
+	}
+
+	if conf.TTL == 0 {
+		conf.TTL = defaultCacheTTL
+	}
+
+	provider = newCachedProvider(provider, conf.TTL, "")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachedProviderFlush(t *testing.T) {
+	useFixtures(t, map[string]Quote{"AAPL": {Price: 180}}, map[string]float64{"EUR": 0.9})
+	path := filepath.Join(t.TempDir(), "cache", "quotes.yaml")
+	cp := newCachedProvider(fixtureProvider{}, time.Minute, path)
+
+	if _, err := cp.Quote("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cp.Rate("EUR"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("cache written before flush: %v", err)
+	}
+
+	if err := cp.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	cached := newCachedProvider(fixtureProvider{}, time.Minute, path)
+	assertFloat(t, "cached price", cached.data.Quotes["AAPL"].Quote.Price, 180)
+	assertFloat(t, "cached rate", cached.data.Rates["EUR"].Rate, 0.9)
+}
+
+func TestCachedProviderStale(t *testing.T) {
+	useFixtures(t, map[string]Quote{"AAPL": {Price: 180}}, nil)
+	cp := newCachedProvider(fixtureProvider{}, 0, "")
+
+	if _, err := cp.Quote("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFixture(t, filepath.Join(fixturesDir, "quotes.yaml"), map[string]Quote{"AAPL": {Price: 181}})
+	q, err := cp.Quote("AAPL")
+	if err != nil || q.Price != 181 || q.StaleSince != nil {
+		t.Fatalf("got %+v, %v, want a new price without TTL", q, err)
+	}
+
+	writeFixture(t, filepath.Join(fixturesDir, "quotes.yaml"), map[string]Quote{})
+	q, err = cp.Quote("AAPL")
+	if err != nil || q.Price != 181 || q.StaleSince == nil {
+		t.Errorf("got %+v, %v, want the stale price", q, err)
+	}
+}
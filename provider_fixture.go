@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fixturesDir is the directory read by the fixture provider. It defaults to
+// the "fixtures" directory next to the config file.
+var fixturesDir string
+
+// fixtureProvider reads quotes, chart bars and rates from local files:
+//
+//	fixtures/quotes.yaml        map of symbol to quote
+//	fixtures/rates.yaml         map of currency to units per USD
+//	fixtures/charts/AAPL.yaml   list of bars
+//	fixtures/rates/EUR.yaml     list of daily bars of a rate
+//
+// Each file can also be written as JSON with a .json extension.
+type fixtureProvider struct{}
+
+func (p fixtureProvider) Quote(symbol string) (Quote, error) {
+	quotes := map[string]Quote{}
+	err := p.read("quotes", &quotes)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	q, ok := quotes[symbol]
+	if !ok {
+		return q, fmt.Errorf("Could not find quote by symbol %s", symbol)
+	}
+
+	if q.Symbol == "" {
+		q.Symbol = symbol
+	}
+
+	return q, nil
+}
+
+func (p fixtureProvider) Chart(symbol string, params ChartParams) ([]Bar, error) {
+	return p.bars(filepath.Join("charts", symbol), params)
+}
+
+// bars reads the bars of a fixture in the range of the params.
+func (p fixtureProvider) bars(name string, params ChartParams) ([]Bar, error) {
+	bars := []Bar{}
+	err := p.read(name, &bars)
+	if err != nil {
+		return bars, err
+	}
+
+	result := []Bar{}
+	for _, b := range bars {
+		if !params.Start.IsZero() && b.Time.Before(params.Start) {
+			continue
+		}
+
+		if !params.End.IsZero() && b.Time.After(params.End) {
+			continue
+		}
+
+		result = append(result, b)
+	}
+
+	return result, nil
+}
+
+func (p fixtureProvider) RateChart(currency string, params ChartParams) ([]Bar, error) {
+	return p.bars(filepath.Join("rates", currency), params)
+}
+
+func (p fixtureProvider) Rate(currency string) (float64, error) {
+	if currency == "USD" {
+		return 1.0, nil
+	}
+
+	rates := map[string]float64{}
+	err := p.read("rates", &rates)
+	if err != nil {
+		return 0, err
+	}
+
+	rate, ok := rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("Could not find rate for currency %s", currency)
+	}
+
+	return rate, nil
+}
+
+func (fixtureProvider) read(name string, in interface{}) error {
+	dir := fixturesDir
+	if dir == "" {
+		dir = "fixtures"
+	}
+
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return readYaml(path, in)
+		}
+	}
+
+	return fmt.Errorf("Fixture does not exist: %s", filepath.Join(dir, name))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/chart"
+	"github.com/piquette/finance-go/datetime"
+	"github.com/piquette/finance-go/quote"
+	"time"
+)
+
+type yahooProvider struct{}
+
+func (yahooProvider) Quote(symbol string) (Quote, error) {
+	result := Quote{}
+	q, err := quote.Get(symbol)
+	if err != nil {
+		return result, err
+	}
+
+	if q == nil {
+		return result, fmt.Errorf("Could not find quote by symbol %s", symbol)
+	}
+
+	result.Price = q.RegularMarketPrice
+	result.Pct = q.RegularMarketChangePercent
+
+	if q.MarketState == "PRE" && q.PreMarketPrice > 0 {
+		result.Price = q.PreMarketPrice
+		result.Pct = q.PreMarketChangePercent
+	} else if q.MarketState == "POST" && q.PostMarketPrice > 0 {
+		result.Price = q.PostMarketPrice
+		result.Pct = q.PostMarketChangePercent
+	}
+
+	result.Symbol = q.Symbol
+	result.State = string(q.MarketState)
+	result.Name = q.ShortName
+	result.Market = q.MarketID
+	result.Timezone = q.ExchangeTimezoneName
+	result.Currency = q.CurrencyID
+
+	return result, nil
+}
+
+func (yahooProvider) Chart(symbol string, params ChartParams) ([]Bar, error) {
+	bars, _, err := yahooChart(symbol, params)
+	return bars, err
+}
+
+// RateChart returns the daily rates of a currency. Yahoo stamps the bars at
+// midnight in the timezone of the exchange, which is the day before in UTC
+// during the London summer time, so the bars are moved to midnight UTC of
+// their date in the exchange timezone.
+func (yahooProvider) RateChart(currency string, params ChartParams) ([]Bar, error) {
+	bars, meta, err := yahooChart(currency+"=X", params)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.FixedZone(meta.Timezone, meta.Gmtoffset)
+	if meta.ExchangeTimezoneName != "" {
+		loc = getLocation(meta.ExchangeTimezoneName)
+	}
+
+	for i, b := range bars {
+		t := b.Time.In(loc)
+		bars[i].Time = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	return bars, nil
+}
+
+func yahooChart(symbol string, params ChartParams) ([]Bar, finance.ChartMeta, error) {
+	p := &chart.Params{
+		Symbol:   symbol,
+		Interval: datetime.Interval(params.Interval),
+	}
+	if !params.Start.IsZero() {
+		p.Start = datetime.FromUnix(int(params.Start.Unix()))
+	}
+	if !params.End.IsZero() {
+		p.End = datetime.FromUnix(int(params.End.Unix()))
+	}
+
+	iter := chart.Get(p)
+
+	bars := []Bar{}
+	for iter.Next() {
+		b := iter.Bar()
+		fl, _ := b.Close.Float64()
+		bars = append(bars, Bar{time.Unix(int64(b.Timestamp), 0), fl})
+	}
+
+	// The meta is only set if the request succeeded.
+	meta, _ := iter.Iter.Meta().(finance.ChartMeta)
+	return bars, meta, iter.Err()
+}
+
+func (p yahooProvider) Rate(currency string) (float64, error) {
+	if currency == "USD" {
+		return 1.0, nil
+	}
+
+	q, err := p.Quote(currency + "=X")
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Price, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quoteWorkers is the max number of concurrent provider requests.
+const quoteWorkers = 8
+
+// Policies for failed quotes: fail aborts, skip leaves the symbol out of the
+// stats and stale uses the last cached price. Only stale writes snapshots.
+const (
+	onQuoteErrorFail  = "fail"
+	onQuoteErrorSkip  = "skip"
+	onQuoteErrorStale = "stale"
+)
+
+// QuoteErrors ...
+type QuoteErrors map[string]error
+
+func (e QuoteErrors) Error() string {
+	msgs := []string{}
+	for _, symbol := range e.symbols() {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", symbol, e[symbol]))
+	}
+
+	return "Could not fetch quotes: " + strings.Join(msgs, ", ")
+}
+
+func (e QuoteErrors) symbols() []string {
+	symbols := []string{}
+	for symbol := range e {
+		symbols = append(symbols, symbol)
+	}
+
+	sort.Strings(symbols)
+	return symbols
+}
+
+// QuoteSet ...
+type QuoteSet struct {
+	Quotes map[string]Quote
+	Rates  map[string]float64
+	Errors map[string]error
+
+	// StaleRates are the rates served from the cache since the time the
+	// provider failed.
+	StaleRates map[string]time.Time
+}
+
+// fetchQuotes fetches each unique symbol and currency once, concurrently.
+func fetchQuotes(symbols []string, currencies []string) QuoteSet {
+	set := QuoteSet{
+		Quotes: make(map[string]Quote),
+		Rates:  make(map[string]float64),
+		Errors: make(map[string]error),
+
+		StaleRates: make(map[string]time.Time),
+	}
+
+	var mu sync.Mutex
+	jobs := []func(){}
+	for _, symbol := range unique(symbols) {
+		symbol := symbol
+		jobs = append(jobs, func() {
+			q, err := getQuote(symbol, true)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				set.Errors[symbol] = err
+			}
+			set.Quotes[symbol] = q
+		})
+	}
+
+	runJobs(jobs)
+
+	// The currencies of the quotes are only known now.
+	for _, q := range set.Quotes {
+		currencies = append(currencies, q.Currency)
+	}
+
+	jobs = []func(){}
+	for _, currency := range unique(currencies) {
+		currency := currency
+		jobs = append(jobs, func() {
+			rate, stale := getRate(currency)
+			mu.Lock()
+			defer mu.Unlock()
+			set.Rates[currency] = rate
+			if stale != nil {
+				set.StaleRates[currency] = *stale
+			}
+		})
+	}
+
+	runJobs(jobs)
+	flushCache()
+	return set
+}
+
+// runJobs runs the jobs on quoteWorkers goroutines and waits for all.
+func runJobs(jobs []func()) {
+	var wg sync.WaitGroup
+	queue := make(chan func())
+
+	for i := 0; i < quoteWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job()
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		queue <- job
+	}
+
+	close(queue)
+	wg.Wait()
+}
+
+// fetchConfQuotes fetches all quotes and rates needed by the investments of
+// a config.
+func fetchConfQuotes(c *Conf) QuoteSet {
+	return fetchQuotes(confSymbols(c), c.currencies())
+}
+
+// check returns an error if the quote of a symbol is unusable under the
+// given policy.
+func (s QuoteSet) check(symbol string, policy string) error {
+	if err, ok := s.Errors[symbol]; ok {
+		return err
+	}
+
+	q := s.Quotes[symbol]
+	if q.StaleSince != nil && policy != onQuoteErrorStale {
+		return fmt.Errorf("%s", formatStale(q))
+	}
+
+	if q.Price == 0 {
+		return fmt.Errorf("Price is zero")
+	}
+
+	return nil
+}
+
+// checkRates returns an error if the rate of the quote or an order of a
+// symbol is unusable under the given policy.
+func (s QuoteSet) checkRates(symbol string, orders []Order, policy string) error {
+	currencies := []string{s.currency(symbol, "")}
+	for _, order := range orders {
+		currencies = append(currencies, s.currency(symbol, order.Currency))
+	}
+
+	for _, currency := range currencies {
+		if err := s.checkRate(currency, policy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPayments returns an error if the rate of a payment of a symbol is
+// unusable under the given policy.
+func (s QuoteSet) checkPayments(symbol string, payments []Payment, policy string) error {
+	for _, payment := range payments {
+		if err := s.checkRate(s.currency(symbol, payment.Currency), policy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRate returns an error if the rate of a currency is missing, or stale
+// and the policy is not stale.
+func (s QuoteSet) checkRate(currency string, policy string) error {
+	if s.currencyFactor(currency) == 0 {
+		return fmt.Errorf("Could not fetch rate for %s", currency)
+	}
+
+	if since, ok := s.StaleRates[currency]; ok && policy != onQuoteErrorStale {
+		return fmt.Errorf("%s rate stale since %s", currency, since.Format("2006-01-02 15:04"))
+	}
+
+	return nil
+}
+
+func (s QuoteSet) currencyFactor(currency string) float64 {
+	if currency != "" && currency != "USD" {
+		return s.rate(currency)
+	}
+
+	return 1.0
+}
+
+func (s QuoteSet) rate(currency string) float64 {
+	if rate, ok := s.Rates[currency]; ok {
+		return rate
+	}
+
+	return getCurrency(currency)
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		result = append(result, v)
+	}
+
+	return result
+}
+
+func (s QuoteSet) list() []Quote {
+	quotes := []Quote{}
+	for _, q := range s.Quotes {
+		quotes = append(quotes, q)
+	}
+
+	sort.Slice(quotes, func(i, j int) bool {
+		return quotes[i].Symbol < quotes[j].Symbol
+	})
+
+	return quotes
+}
+
+func formatStale(q Quote) string {
+	if q.StaleSince == nil {
+		return ""
+	}
+
+	return "stale since " + q.StaleSince.Format("2006-01-02 15:04")
+}
+
+func printStale(quotes QuoteSet) {
+	for _, q := range quotes.list() {
+		if q.StaleSince != nil {
+			fmt.Fprintf(notes(), "%s: %s\n", q.Symbol, formatStale(q))
+		}
+	}
+
+	currencies := []string{}
+	for currency := range quotes.StaleRates {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		fmt.Fprintf(notes(), "%s: rate stale since %s\n", currency, quotes.StaleRates[currency].Format("2006-01-02 15:04"))
+	}
+}
+
+func checkQuoteErrorPolicy(policy string) error {
+	switch policy {
+	case onQuoteErrorFail, onQuoteErrorSkip, onQuoteErrorStale:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown quote error policy %q, allowed: fail,skip,stale", policy)
+}
+
+func printQuoteErrors(errs QuoteErrors) {
+	if outputFormat != outputTable {
+		for _, symbol := range errs.symbols() {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", symbol, errs[symbol])
+		}
+
+		return
+	}
+
+	table := newTable()
+	table.SetHeader([]string{"Symbol", "Error"})
+
+	for _, symbol := range errs.symbols() {
+		table.Rich([]string{symbol, errs[symbol].Error()}, []tablewriter.Colors{
+			{tablewriter.Bold, tablewriter.FgRedColor},
+		})
+	}
+
+	table.Render(nil)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// SnapshotConf ...
+type SnapshotConf struct {
+	// Details stores the units, price, rate and value of every symbol.
+	Details bool
+	// OnlyChanged writes a snapshot only if a value changed since the last.
+	OnlyChanged bool `yaml:"only_changed"`
+	// Interval is the min time between two snapshots.
+	Interval  time.Duration
+	Retention []RetentionRule
+	// Store is dir (default), jsonl or csv, and File the file of the jsonl
+	// and csv store.
+	Store string
+	File  string
+}
+
+// RetentionRule keeps one snapshot per interval (all, hourly, daily, weekly,
+// monthly or none) of the snapshots younger than Age. The first matching
+// rule applies, a rule without age matches all snapshots.
+type RetentionRule struct {
+	Age  string
+	Keep string
+}
+
+// snapshotVersion is the version of snapshots with symbol details. Older
+// snapshots have no version and are read the same way.
+const snapshotVersion = 2
+
+func shouldWriteSnapshot(out Out, history []Out, conf SnapshotConf) bool {
+	if len(history) == 0 {
+		return true
+	}
+
+	last := history[0]
+	for _, h := range history {
+		if h.Date.After(last.Date) {
+			last = h
+		}
+	}
+
+	if conf.Interval > 0 && out.Date.Sub(last.Date) < conf.Interval {
+		return false
+	}
+
+	if conf.OnlyChanged && reflect.DeepEqual(outValues(out), outValues(last)) {
+		return false
+	}
+
+	return true
+}
+
+func validateRetention(rules []RetentionRule) error {
+	for _, rule := range rules {
+		if rule.Age != "" {
+			if _, err := parsePeriod(rule.Age); err != nil {
+				return err
+			}
+		}
+
+		switch rule.Keep {
+		case "all", "hourly", "daily", "weekly", "monthly", "none":
+		default:
+			return fmt.Errorf("Unknown retention %q, allowed: all,hourly,daily,weekly,monthly,none", rule.Keep)
+		}
+	}
+
+	return nil
+}
+
+// expiredSnapshots returns the snapshots removed by the retention rules.
+// Within an interval the newest snapshot is kept. Snapshots without a
+// matching rule are kept.
+func expiredSnapshots(snapshots []Out, rules []RetentionRule, now time.Time) []Out {
+	sorted := append([]Out{}, snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.After(sorted[j].Date)
+	})
+
+	expired := []Out{}
+	seen := make(map[string]bool)
+
+	for _, s := range sorted {
+		i, rule, ok := retentionRule(rules, now.Sub(s.Date))
+		if !ok || rule.Keep == "all" {
+			continue
+		}
+
+		key := fmt.Sprintf("%d %s", i, retentionBucket(s.Date, rule.Keep))
+		if rule.Keep == "none" || seen[key] {
+			expired = append(expired, s)
+			continue
+		}
+
+		seen[key] = true
+	}
+
+	return expired
+}
+
+func retentionRule(rules []RetentionRule, age time.Duration) (int, RetentionRule, bool) {
+	for i, rule := range rules {
+		if rule.Age == "" {
+			return i, rule, true
+		}
+
+		max, err := parsePeriod(rule.Age)
+		if err == nil && age < max {
+			return i, rule, true
+		}
+	}
+
+	return 0, RetentionRule{}, false
+}
+
+func retentionBucket(t time.Time, keep string) string {
+	if keep == "hourly" {
+		return t.Local().Format("2006-01-02T15")
+	}
+
+	return historyBucket(t, keep)
+}
+
+// compactHistory removes the expired snapshots and returns the number of
+// removed and all snapshots.
+func compactHistory(conf *Conf, configFile string, dryRun bool) (int, int, error) {
+	store, err := openStore(conf.Snapshot, configFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	snapshots, err := store.Load()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	expired := expiredSnapshots(snapshots, conf.Snapshot.Retention, time.Now())
+	if !dryRun && len(expired) > 0 {
+		err = store.Remove(expired)
+		if err != nil {
+			return 0, len(snapshots), err
+		}
+	}
+
+	return len(expired), len(snapshots), nil
+}
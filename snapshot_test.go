@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func snapshotsAt(dates ...string) []Out {
+	outs := []Out{}
+	for _, d := range dates {
+		t, err := time.ParseInLocation("2006-01-02 15:04", d, time.Local)
+		if err != nil {
+			panic(err)
+		}
+
+		outs = append(outs, Out{Date: t})
+	}
+
+	return outs
+}
+
+func dates(outs []Out) []string {
+	result := []string{}
+	for _, out := range outs {
+		result = append(result, out.Date.Format("2006-01-02 15:04"))
+	}
+
+	return result
+}
+
+func TestExpiredSnapshots(t *testing.T) {
+	now := snapshotsAt("2022-06-15 12:00")[0].Date
+	snapshots := snapshotsAt(
+		"2022-01-05 09:00",
+		"2022-06-15 10:00",
+		"2022-06-10 10:00",
+		"2022-06-15 08:00",
+		"2022-01-20 09:00",
+		"2022-06-10 18:00",
+	)
+
+	tests := []struct {
+		rules []RetentionRule
+		want  []string
+	}{
+		{
+			[]RetentionRule{{Age: "2d", Keep: "all"}, {Age: "30d", Keep: "daily"}, {Keep: "monthly"}},
+			[]string{"2022-06-10 10:00", "2022-01-05 09:00"},
+		},
+		{
+			[]RetentionRule{{Age: "2d", Keep: "daily"}, {Age: "30d", Keep: "none"}},
+			[]string{"2022-06-15 08:00", "2022-06-10 18:00", "2022-06-10 10:00"},
+		},
+		{
+			[]RetentionRule{{Age: "1h", Keep: "none"}},
+			[]string{},
+		},
+	}
+
+	for _, test := range tests {
+		got := dates(expiredSnapshots(snapshots, test.rules, now))
+		if len(got) != len(test.want) {
+			t.Errorf("%v: expired %v, want %v", test.rules, got, test.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: expired %v, want %v", test.rules, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestValidateRetention(t *testing.T) {
+	if err := validateRetention([]RetentionRule{{Age: "1mo", Keep: "weekly"}, {Keep: "none"}}); err != nil {
+		t.Error(err)
+	}
+
+	if err := validateRetention([]RetentionRule{{Keep: "yearly"}}); err == nil {
+		t.Error("got no error for yearly")
+	}
+
+	if err := validateRetention([]RetentionRule{{Age: "3x", Keep: "daily"}}); err == nil {
+		t.Error("got no error for age 3x")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SnapshotStore ...
+type SnapshotStore interface {
+	// Load returns all snapshots of the store.
+	Load() ([]Out, error)
+	// Append adds a snapshot to the store.
+	Append(out Out) error
+	// Remove deletes the snapshots with the same dates from the store.
+	Remove(snapshots []Out) error
+}
+
+var stores = map[string]func(path string) SnapshotStore{
+	"dir":   func(path string) SnapshotStore { return dirStore{path} },
+	"jsonl": func(path string) SnapshotStore { return jsonlStore{path} },
+	"csv":   func(path string) SnapshotStore { return csvStore{path} },
+}
+
+func storeNames() []string {
+	names := []string{}
+	for name := range stores {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func validateStore(conf SnapshotConf) error {
+	if _, ok := stores[conf.Store]; !ok && conf.Store != "" {
+		return fmt.Errorf("Unknown snapshot store %q, allowed: %s", conf.Store, strings.Join(storeNames(), ","))
+	}
+
+	return nil
+}
+
+// openStore returns the snapshot store of a config. The dir store writes one
+// file per snapshot to the finances dir, the others append to a single file,
+// by default history.jsonl or history.csv in the finances dir. A relative
+// file is relative to the config.
+func openStore(conf SnapshotConf, configFile string) (SnapshotStore, error) {
+	name := conf.Store
+	if name == "" {
+		name = "dir"
+	}
+
+	create, ok := stores[name]
+	if !ok {
+		return nil, validateStore(conf)
+	}
+
+	dir, err := getOutDir(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "dir" {
+		return create(dir), nil
+	}
+
+	path := conf.File
+	if path == "" {
+		path = filepath.Join(dir, "history."+name)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configFile), path)
+	}
+
+	return create(path), nil
+}
+
+// loadHistory returns the snapshots of the store of a config, or none if
+// there is no finances dir.
+func loadHistory(conf *Conf, configFile string) []Out {
+	store, err := openStore(conf.Snapshot, configFile)
+	if err != nil {
+		return []Out{}
+	}
+
+	outs, err := store.Load()
+	if err != nil {
+		log.Println(err)
+		return []Out{}
+	}
+
+	return outs
+}
+
+// writeSnapshot appends a snapshot to the store of a config. Symbol details
+// are only stored if enabled.
+func writeSnapshot(out Out, conf *Conf, configFile string) error {
+	// Lots are part of the output, not of snapshots. Symbols hold the
+	// position of each symbol if details are enabled.
+	out.Stocks.Details = nil
+	out.Assets.Details = nil
+	out.Crypto.Details = nil
+	if conf.Snapshot.Details {
+		out.Version = snapshotVersion
+	} else {
+		out.Stocks.Symbols = nil
+		out.Assets.Symbols = nil
+		out.Crypto.Symbols = nil
+	}
+
+	store, err := openStore(conf.Snapshot, configFile)
+	if err != nil {
+		return err
+	}
+
+	return store.Append(out)
+}
+
+// migrateHistory copies the snapshots of the finances dir to another store
+// and returns their number. The target store must be empty.
+func migrateHistory(conf SnapshotConf, configFile string) (int, error) {
+	if conf.Store == "" || conf.Store == "dir" {
+		return 0, fmt.Errorf("Migrate to jsonl or csv, not to dir")
+	}
+
+	source, err := openStore(SnapshotConf{}, configFile)
+	if err != nil {
+		return 0, err
+	}
+
+	target, err := openStore(conf, configFile)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := target.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(existing) > 0 {
+		return 0, fmt.Errorf("Target store has already %d snapshots", len(existing))
+	}
+
+	outs, err := source.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	sortByDate(outs)
+	for _, out := range outs {
+		err = target.Append(out)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(outs), nil
+}
+
+func sortByDate(outs []Out) {
+	sort.SliceStable(outs, func(i, j int) bool {
+		return outs[i].Date.Before(outs[j].Date)
+	})
+}
+
+// containsDate reports whether a snapshot with the date of out is in outs.
+func containsDate(outs []Out, out Out) bool {
+	for _, o := range outs {
+		if o.Date.Equal(out.Date) {
+			return true
+		}
+	}
+
+	return false
+}
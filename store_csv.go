@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvStore appends every value of a snapshot as a date,field,value row to a
+// single file, so new fields don't change the columns. Symbol details are not
+// stored.
+type csvStore struct {
+	path string
+}
+
+var csvHeader = []string{"date", "field", "value"}
+
+func (s csvStore) Load() ([]Out, error) {
+	outs := make([]Out, 0)
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return outs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("in file %q: %v", s.path, err)
+	}
+
+	dates := []string{}
+	values := make(map[string]map[string]float64)
+	for i, record := range records {
+		if i == 0 && record[0] == csvHeader[0] {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("in file %q line %d: %v", s.path, i+1, err)
+		}
+
+		if _, ok := values[record[0]]; !ok {
+			dates = append(dates, record[0])
+			values[record[0]] = make(map[string]float64)
+		}
+
+		values[record[0]][record[1]] = value
+	}
+
+	for _, date := range dates {
+		out, err := csvOut(date, values[date])
+		if err != nil {
+			return nil, fmt.Errorf("in file %q: %v", s.path, err)
+		}
+
+		outs = append(outs, out)
+	}
+
+	return outs, nil
+}
+
+// csvOut builds a snapshot from the values flattened by outValues.
+func csvOut(date string, values map[string]float64) (Out, error) {
+	out := Out{}
+	fields := make(map[string]interface{})
+	for name, value := range values {
+		keys := strings.Split(name, ".")
+		m := fields
+		for _, key := range keys[:len(keys)-1] {
+			if _, ok := m[key].(map[string]interface{}); !ok {
+				m[key] = make(map[string]interface{})
+			}
+
+			m = m[key].(map[string]interface{})
+		}
+
+		m[keys[len(keys)-1]] = value
+	}
+
+	err := yaml.Unmarshal(yamlToBytes(fields), &out)
+	if err != nil {
+		return out, err
+	}
+
+	out.Date, err = time.Parse(time.RFC3339Nano, date)
+	return out, err
+}
+
+func (s csvStore) rows(out Out) [][]string {
+	values := outValues(out)
+	date := out.Date.Format(time.RFC3339Nano)
+	rows := [][]string{}
+	for _, name := range outValueNames(values) {
+		rows = append(rows, []string{date, name, strconv.FormatFloat(values[name], 'f', -1, 64)})
+	}
+
+	return rows
+}
+
+func (s csvStore) Append(out Out) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if info, err := os.Stat(s.path); err != nil || info.Size() == 0 {
+		w.Write(csvHeader)
+	}
+
+	w.WriteAll(s.rows(out))
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	return appendFile(s.path, buf.Bytes())
+}
+
+func (s csvStore) Remove(snapshots []Out) error {
+	outs, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvHeader)
+	for _, out := range outs {
+		if !containsDate(snapshots, out) {
+			w.WriteAll(s.rows(out))
+		}
+	}
+
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	return replaceFile(s.path, buf.Bytes())
+}
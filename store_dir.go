@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dirStore writes every snapshot to a YAML file named by its date.
+type dirStore struct {
+	dir string
+}
+
+func (s dirStore) files() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".yaml") {
+			continue
+		}
+
+		paths = append(paths, filepath.Join(s.dir, f.Name()))
+	}
+
+	return paths, nil
+}
+
+// Load skips files which could not be read.
+func (s dirStore) Load() ([]Out, error) {
+	paths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	outs := make([]Out, 0)
+	for _, path := range paths {
+		out := Out{}
+		err := readYaml(path, &out)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		outs = append(outs, out)
+	}
+
+	return outs, nil
+}
+
+func (s dirStore) Append(out Out) error {
+	target := filepath.Join(s.dir, out.Date.Format(time.RFC3339)+".yaml")
+	return ioutil.WriteFile(target, yamlToBytes(&out), 0644)
+}
+
+func (s dirStore) Remove(snapshots []Out) error {
+	paths, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		out := Out{}
+		if readYaml(path, &out) != nil || !containsDate(snapshots, out) {
+			continue
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// jsonlStore appends every snapshot as a JSON line to a single file.
+type jsonlStore struct {
+	path string
+}
+
+func (s jsonlStore) Load() ([]Out, error) {
+	outs := make([]Out, 0)
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return outs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		out := Out{}
+		err := json.Unmarshal(scanner.Bytes(), &out)
+		if err != nil {
+			return nil, fmt.Errorf("in file %q line %d: %v", s.path, line, err)
+		}
+
+		outs = append(outs, out)
+	}
+
+	return outs, scanner.Err()
+}
+
+func (s jsonlStore) Append(out Out) error {
+	line, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	return appendFile(s.path, append(line, '\n'))
+}
+
+func (s jsonlStore) Remove(snapshots []Out) error {
+	outs, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, out := range outs {
+		if containsDate(snapshots, out) {
+			continue
+		}
+
+		line, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(append(line, '\n'))
+	}
+
+	return replaceFile(s.path, buf.Bytes())
+}
+
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// replaceFile writes to a temp file first, so the history is never left
+// half written.
+func replaceFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
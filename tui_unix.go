@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize sends a signal when the terminal is resized.
+func notifyResize(c chan os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
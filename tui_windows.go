@@ -0,0 +1,9 @@
+package main
+
+import (
+	"os"
+)
+
+// notifyResize does nothing, windows has no resize signal.
+func notifyResize(c chan os.Signal) {
+}